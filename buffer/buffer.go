@@ -26,15 +26,17 @@ package buffer
 
 import (
 	"bytes"
-	"fmt"
-	"unicode"
+	"github.com/mdhender/qif2json/date"
+	"github.com/mdhender/qif2json/decimal"
 	"unicode/utf8"
 )
 
 type Buffer struct {
-	Line   int
-	Col    int
-	Buffer []byte
+	Line    int
+	Col     int
+	Buffer  []byte
+	Dates   date.Parser    // if nil, date.Default is used
+	Numbers decimal.Parser // if nil, decimal.US is used
 }
 
 // NewBuffer returns a new buffer with a copy of the input.
@@ -43,7 +45,7 @@ func NewBuffer(input []byte) (Buffer, error) {
 	for offset < len(input) {
 		r, w := utf8.DecodeRune(input[offset:])
 		if r == utf8.RuneError {
-			return Buffer{}, fmt.Errorf("utf8: import: invalid utf-8 character on line %d, col %d", line, col)
+			return Buffer{}, &ParseError{Line: line, Col: col, Err: ErrInvalidCharacter}
 		} else if r == '\r' {
 			offset += w
 			continue
@@ -58,69 +60,77 @@ func NewBuffer(input []byte) (Buffer, error) {
 	return Buffer{Buffer: b, Line: 1}, nil
 }
 
-// Date will accept a date string which looks like
-//    digit digit? slash (space digit) digit tic digit digit
-func (buf Buffer) Date(flag string) ([]byte, Buffer) {
+// Amount will accept a number only if the flag matches.
+// The text to the end of the line is parsed as a decimal number.
+func (buf Buffer) Amount(flag string) (*decimal.Decimal, Buffer, error) {
 	saved := buf
 
 	if !bytes.HasPrefix(buf.Buffer, []byte(flag)) {
-		return nil, buf
+		return nil, buf, nil
 	}
 	// skip the flag (we don't return it as part of the lexeme)
 	buf.Buffer, buf.Col = buf.Buffer[len(flag):], buf.Col+len(flag)
+	line, col := buf.Line, buf.Col
 
-	var length, w int
-	var r rune
+	// read the text and consume to the end of the line
+	var text []byte
+	text, buf = buf.ToEndOfLine()
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); !unicode.IsDigit(r) { // digit
-		return nil, saved
+	amount, err := buf.ParseAmount(text)
+	if err != nil {
+		return nil, saved, &ParseError{Line: line, Col: col, Err: err}
 	}
-	length += w
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); unicode.IsDigit(r) { // digit?
-		length += w
-	}
+	// return the amount and updated buffer
+	return &amount, buf, nil
+}
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); r != '/' { // slash
-		return nil, saved
-	}
-	length += w
+// Date will accept a date only if the flag matches.
+// The text to the end of the line is parsed with the buffer's date parser
+// and the lexeme is the date formatted as yyyy/mm/dd.
+func (buf Buffer) Date(flag string) ([]byte, Buffer, error) {
+	saved := buf
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); !(r == ' ' || unicode.IsDigit(r)) { // (space digit)
-		return nil, saved
+	if !bytes.HasPrefix(buf.Buffer, []byte(flag)) {
+		return nil, buf, nil
 	}
-	length += w
+	// skip the flag (we don't return it as part of the lexeme)
+	buf.Buffer, buf.Col = buf.Buffer[len(flag):], buf.Col+len(flag)
+	line, col := buf.Line, buf.Col
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); !unicode.IsDigit(r) { // digit
-		return nil, saved
-	}
-	length += w
+	// read the text and consume to the end of the line
+	var text []byte
+	text, buf = buf.ToEndOfLine()
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); r != '\'' { // tic
-		return nil, saved
+	lexeme, err := buf.ParseDate(text)
+	if err != nil {
+		return nil, saved, &ParseError{Line: line, Col: col, Err: err}
 	}
-	length += w
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); !unicode.IsDigit(r) { // digit
-		return nil, saved
-	}
-	length += w
+	// return the lexeme and updated buffer
+	return lexeme, buf, nil
+}
 
-	if r, w = utf8.DecodeRune(buf.Buffer[length:]); !unicode.IsDigit(r) { // digit
-		return nil, saved
+// ParseAmount returns the text as a decimal number.
+func (buf Buffer) ParseAmount(text []byte) (decimal.Decimal, error) {
+	parser := buf.Numbers
+	if parser == nil {
+		parser = decimal.US
 	}
-	length += w
+	return parser.Parse(string(text))
+}
 
-	lexeme := bdate(buf.Buffer[:length])
-	if lexeme == "****/**/**" {
-		return nil, saved
+// ParseDate returns the text as a date formatted as yyyy/mm/dd.
+func (buf Buffer) ParseDate(text []byte) ([]byte, error) {
+	parser := buf.Dates
+	if parser == nil {
+		parser = date.Default
 	}
-
-	// consume to the end of the line
-	_, buf = buf.ToEndOfLine()
-
-	// return the lexeme and updated buffer
-	return []byte(lexeme), buf
+	lexeme, err := parser.Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(lexeme), nil
 }
 
 // Field will accept text to the end of the line only if the flag matches.
@@ -227,44 +237,8 @@ func (buf Buffer) ToEndOfLine() ([]byte, Buffer) {
 	return lexeme, buf
 }
 
-// bdate translates QIF date to a string with the date formatted as yyyy/mm/dd
-// The QIF date is formatted as mm/dd'yy. The month can be one or two digits
-// (eg, January is `1` while October is `10`). The day must be two characters,
-// but the first character may be a space instead of a zero. For example,
-// `01` and ` 1` are both the first day of the month. The year must be two
-// digits, and we're assuming it is always in the 21st century (eg, `16` is
-// converted to 2016, not 1916).
-func bdate(b []byte) string {
-	// 9/ 3'16 -> 2016/09/03
-	// 9/13'16 -> 2016/09/13
-	if len(b) == 7 && b[1] == '/' && b[4] == '\'' {
-		mm, dd, yy := b[0:1], b[2:4], b[5:]
-		return fmt.Sprintf("%4d/%02d/%02d", bint(yy)+2000, bint(mm), bint(dd))
-	}
-
-	// 12/ 9'16 -> 2016/12/09
-	// 12/19'16 -> 2016/12/19
-	if len(b) == 8 && b[2] == '/' && b[5] == '\'' {
-		mm, dd, yy := b[0:2], b[3:6], b[6:]
-		return fmt.Sprintf("%4d/%02d/%02d", bint(yy)+2000, bint(mm), bint(dd))
-	}
-
-	// invalid date
-	return "****/**/**"
-}
-
 func bdup(src []byte) []byte {
 	dst := make([]byte, len(src))
 	copy(dst, src)
 	return dst
 }
-
-// bint converts a slice to an int.
-func bint(b []byte) (i int) {
-	for pos := 0; pos < len(b); pos++ {
-		if '0' <= b[pos] && b[pos] <= '9' {
-			i = i*10 + int(b[pos]) - '0'
-		}
-	}
-	return i
-}
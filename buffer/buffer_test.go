@@ -0,0 +1,71 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package buffer
+
+import (
+	"errors"
+	"github.com/mdhender/qif2json/date"
+	"testing"
+)
+
+func TestDate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		dates  date.Parser
+		input  string
+		want   string // the lexeme, if the date is valid
+		reason string // the reason, if it isn't
+	}{
+		{"default", nil, "D12/31'98\nT1.00\n", "1998/12/31", ""},
+		{"day first", date.Grammar{DayFirst: true}, "D31/12/1998\n", "1998/12/31", ""},
+		{"month out of range", nil, "D13/1/2021\n", "", "month out of range"},
+		{"no layout", nil, "Dyesterday\n", "", "does not match any layout"},
+	} {
+		buf := Buffer{Line: 3, Col: 1, Buffer: []byte(tc.input), Dates: tc.dates}
+		lexeme, rest, err := buf.Date("D")
+		if tc.reason == "" {
+			if err != nil {
+				t.Errorf("%s: Date: %v", tc.name, err)
+			} else if string(lexeme) != tc.want {
+				t.Errorf("%s: Date = %q, want %q", tc.name, lexeme, tc.want)
+			} else if rest.Line != 4 || rest.Col != 1 {
+				t.Errorf("%s: Date left the buffer at %d:%d, want 4:1", tc.name, rest.Line, rest.Col)
+			}
+			continue
+		}
+		// the error is at the start of the text, after the flag
+		var pe *ParseError
+		var de *date.Error
+		if !errors.As(err, &pe) || !errors.As(err, &de) {
+			t.Errorf("%s: Date: %v, want a ParseError wrapping a date.Error", tc.name, err)
+		} else if pe.Line != 3 || pe.Col != 2 {
+			t.Errorf("%s: Date: error at %d:%d, want 3:2", tc.name, pe.Line, pe.Col)
+		} else if de.Reason != tc.reason {
+			t.Errorf("%s: Date: reason %q, want %q", tc.name, de.Reason, tc.reason)
+		} else if rest.Line != buf.Line || string(rest.Buffer) != tc.input {
+			t.Errorf("%s: Date consumed input on error", tc.name)
+		}
+	}
+}
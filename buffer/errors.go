@@ -0,0 +1,94 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The errors wrapped by a ParseError.
+var (
+	ErrInvalidCharacter  = errors.New("invalid utf-8 character")
+	ErrMissingField      = errors.New("missing field")
+	ErrMissingTerminator = errors.New("missing record terminator")
+	ErrUnexpectedInput   = errors.New("unexpected input")
+)
+
+// ParseError reports a problem with the input.
+// Use errors.As to retrieve it from an error returned by a reader.
+type ParseError struct {
+	Line    int
+	Col     int
+	Section string // name of the section or record being read, if known
+	Field   string // name of the field being read, if known
+	Err     error  // the underlying error
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d:%d: ", e.Line, e.Col))
+	if e.Section != "" {
+		sb.WriteString(e.Section + ": ")
+	}
+	if e.Field != "" {
+		sb.WriteString(e.Field + ": ")
+	}
+	sb.WriteString(e.Err.Error())
+	return sb.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// UnsupportedSectionError reports a section header that is not supported.
+type UnsupportedSectionError struct {
+	Line   int
+	Col    int
+	Header string // the header, without the leading '!'
+}
+
+func (e *UnsupportedSectionError) Error() string {
+	return fmt.Sprintf("%d:%d: unsupported section %q", e.Line, e.Col, e.Header)
+}
+
+// FieldError returns err as a ParseError for the section and field.
+// If err is already a ParseError, the section and field are only set if they are empty.
+// Otherwise, the error is wrapped in a new ParseError at the buffer's position.
+func (buf Buffer) FieldError(err error, section, field string) error {
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		return &ParseError{Line: buf.Line, Col: buf.Col, Section: section, Field: field, Err: err}
+	}
+	if pe.Section == "" {
+		pe.Section = section
+	}
+	if pe.Field == "" {
+		pe.Field = field
+	}
+	return pe
+}
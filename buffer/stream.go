@@ -0,0 +1,159 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/mdhender/qif2json/date"
+	"github.com/mdhender/qif2json/decimal"
+	"io"
+	"unicode/utf8"
+)
+
+// Stream splits a QIF input into chunks without reading the whole input into memory.
+// Each chunk is either a single section header (a line starting with '!') or a
+// single record (every line up to and including the '^' terminator).
+type Stream struct {
+	// Dates is the date parser for every chunk.
+	Dates date.Parser
+	// Numbers is the number parser for every chunk.
+	Numbers decimal.Parser
+
+	r    *bufio.Reader
+	line int // number of lines read from the input
+
+	// a header line that ended the previous record
+	pending     []byte
+	pendingLine int
+
+	// true if the rest of a record with an invalid character must be skipped
+	resync bool
+}
+
+// NewStream returns a new stream reading from the input.
+func NewStream(r io.Reader) *Stream {
+	return &Stream{r: bufio.NewReader(r)}
+}
+
+// Next returns the next chunk from the input.
+// Blank lines between chunks are skipped.
+// It returns io.EOF when the input is exhausted.
+//
+// If a line contains an invalid character, Next returns a ParseError.
+// The next call to Next skips the rest of that record, including any
+// other invalid lines in it.
+func (s *Stream) Next() (Buffer, error) {
+	chunk := Buffer{Dates: s.Dates, Numbers: s.Numbers}
+	for {
+		line, lineNo, err := s.readLine()
+		_, invalid := err.(*ParseError)
+		if s.resync && (err == nil || invalid) {
+			// only the first invalid line of a record is reported
+			switch {
+			case line[0] == '!' && invalid:
+				// an invalid header starts a new section, so it is reported
+				s.resync = false
+			case line[0] == '!':
+				s.pending, s.pendingLine, s.resync = line, lineNo, false
+				continue
+			case line[0] == '^':
+				s.resync = false
+				continue
+			default:
+				continue
+			}
+		}
+		if err == io.EOF {
+			if len(chunk.Buffer) != 0 {
+				return chunk, nil
+			}
+			return Buffer{}, io.EOF
+		} else if err != nil {
+			if invalid {
+				// skip the rest of the record, unless the bad line ended it
+				s.resync = len(line) == 0 || (line[0] != '^' && line[0] != '!')
+			}
+			return Buffer{}, err
+		}
+
+		if len(chunk.Buffer) == 0 {
+			if len(bytes.TrimSpace(line)) == 0 {
+				continue
+			}
+			chunk.Line, chunk.Col = lineNo, 1
+			if line[0] == '!' {
+				chunk.Buffer = line
+				return chunk, nil
+			}
+		} else if line[0] == '!' {
+			// a header ends the record without being part of it
+			s.pending, s.pendingLine = line, lineNo
+			return chunk, nil
+		}
+
+		chunk.Buffer = append(chunk.Buffer, line...)
+		if line[0] == '^' {
+			return chunk, nil
+		}
+	}
+}
+
+// readLine returns the next line of input with carriage returns removed.
+// The line is always terminated by a new-line.
+func (s *Stream) readLine() ([]byte, int, error) {
+	if s.pending != nil {
+		line, lineNo := s.pending, s.pendingLine
+		s.pending, s.pendingLine = nil, 0
+		return line, lineNo, nil
+	}
+
+	input, err := s.r.ReadBytes('\n')
+	if len(input) == 0 {
+		if err == nil {
+			err = io.EOF
+		}
+		return nil, 0, err
+	} else if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	s.line++
+
+	line, col := make([]byte, 0, len(input)+1), 0
+	for offset := 0; offset < len(input); {
+		r, w := utf8.DecodeRune(input[offset:])
+		if r == utf8.RuneError {
+			return input, s.line, &ParseError{Line: s.line, Col: col, Err: ErrInvalidCharacter}
+		} else if r != '\r' {
+			line, col = append(line, input[offset:offset+w]...), col+1
+		}
+		offset += w
+	}
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		line = append(line, '\n')
+	}
+
+	return line, s.line, nil
+}
@@ -0,0 +1,124 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package buffer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	// a chunk is the line it starts on and its text,
+	// or the position of the error that was returned instead
+	type chunk struct {
+		line int
+		text string
+		err  string
+	}
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []chunk
+	}{
+		{"records", "!Type:Bank\nD1/1/2021\nT1.00\n^\nD1/2/2021\n^\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{2, "D1/1/2021\nT1.00\n^\n", ""},
+			{5, "D1/2/2021\n^\n", ""},
+		}},
+		{"carriage returns and blank lines", "!Type:Bank\r\n\r\nD1/1/2021\r\n^\r\n\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{3, "D1/1/2021\n^\n", ""},
+		}},
+		{"no final new-line or terminator", "!Type:Bank\nD1/1/2021", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{2, "D1/1/2021\n", ""},
+		}},
+		{"header ends a record", "!Type:Bank\nD1/1/2021\n!Type:Cash\nD1/2/2021\n^\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{2, "D1/1/2021\n", ""},
+			{3, "!Type:Cash\n", ""},
+			{4, "D1/2/2021\n^\n", ""},
+		}},
+		{"resync at the terminator", "!Type:Bank\nD1/1/2021\nPbad\xff\nT1.00\n^\nD1/2/2021\n^\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{0, "", "3:4: invalid utf-8 character"},
+			{6, "D1/2/2021\n^\n", ""},
+		}},
+		{"resync at a header", "!Type:Bank\nPbad\xff\nT1.00\n!Type:Cash\nD1/2/2021\n^\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{0, "", "2:4: invalid utf-8 character"},
+			{4, "!Type:Cash\n", ""},
+			{5, "D1/2/2021\n^\n", ""},
+		}},
+		{"one error per record", "!Type:Bank\nPbad\xff\nMbad\xff\n^\xff\nD1/2/2021\n^\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{0, "", "2:4: invalid utf-8 character"},
+			{5, "D1/2/2021\n^\n", ""},
+		}},
+		{"bad header after a bad line", "!Type:Bank\nPbad\xff\n!Type:Cash\xff\nD1/2/2021\n^\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{0, "", "2:4: invalid utf-8 character"},
+			{0, "", "3:10: invalid utf-8 character"},
+			{4, "D1/2/2021\n^\n", ""},
+		}},
+		{"bad terminator doesn't resync", "!Type:Bank\nD1/1/2021\n^\xff\nD1/2/2021\n^\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{0, "", "3:1: invalid utf-8 character"},
+			{4, "D1/2/2021\n^\n", ""},
+		}},
+		{"resync to the end of input", "!Type:Bank\nPbad\xff\nT1.00\n", []chunk{
+			{1, "!Type:Bank\n", ""},
+			{0, "", "2:4: invalid utf-8 character"},
+		}},
+	} {
+		s := NewStream(strings.NewReader(tc.input))
+		var got []chunk
+		for len(got) <= len(tc.want) {
+			buf, err := s.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				var pe *ParseError
+				if !errors.As(err, &pe) || !errors.Is(err, ErrInvalidCharacter) {
+					t.Errorf("%s: Next: unexpected error %v", tc.name, err)
+				}
+				got = append(got, chunk{err: err.Error()})
+				continue
+			}
+			got = append(got, chunk{line: buf.Line, text: string(buf.Buffer)})
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got %d chunks %q, want %d", tc.name, len(got), got, len(tc.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: chunk %d: got %q, want %q", tc.name, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
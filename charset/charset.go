@@ -0,0 +1,286 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package charset transcodes QIF input to UTF-8.
+// Quicken for Windows and MS Money write Windows-1252, so
+// payees like "café" must be transcoded before the input is lexed.
+package charset
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// The encodings that may be passed to NewReader.
+const (
+	Auto        = "auto"
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Windows1252 = "windows-1252"
+	Latin1      = "iso-8859-1"
+)
+
+// Name returns the canonical name of an encoding.
+// It accepts common aliases such as "utf8", "cp1252" and "latin1".
+func Name(encoding string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(encoding))
+	name = strings.NewReplacer("-", "", "_", "", " ", "").Replace(name)
+	switch name {
+	case "", "auto":
+		return Auto, nil
+	case "utf8":
+		return UTF8, nil
+	case "utf16", "utf16le":
+		return UTF16LE, nil
+	case "utf16be":
+		return UTF16BE, nil
+	case "windows1252", "cp1252", "win1252", "ansi":
+		return Windows1252, nil
+	case "iso88591", "latin1", "l1":
+		return Latin1, nil
+	}
+	return "", fmt.Errorf("charset: unknown encoding %q", encoding)
+}
+
+// Detect makes a best-effort guess at the encoding of the input from its prefix.
+// A byte order mark is always honored. Otherwise, a prefix with a NUL in every
+// other byte is taken to be UTF-16 and a prefix that is not valid UTF-8 is taken
+// to be Windows-1252.
+func Detect(prefix []byte) string {
+	switch {
+	case bytes.HasPrefix(prefix, bomUTF8):
+		return UTF8
+	case bytes.HasPrefix(prefix, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(prefix, bomUTF16BE):
+		return UTF16BE
+	}
+
+	var evenNuls, oddNuls int
+	for i, b := range prefix {
+		if b == 0 && i%2 == 0 {
+			evenNuls++
+		} else if b == 0 {
+			oddNuls++
+		}
+	}
+	if pairs := len(prefix) / 2; pairs != 0 {
+		if oddNuls > pairs/2 && evenNuls == 0 {
+			return UTF16LE
+		} else if evenNuls > pairs/2 && oddNuls == 0 {
+			return UTF16BE
+		}
+	}
+
+	// ignore a multi-byte character that was cut off at the end of the prefix
+	for i := 1; i < utf8.UTFMax && i <= len(prefix); i++ {
+		if utf8.RuneStart(prefix[len(prefix)-i]) {
+			if !utf8.FullRune(prefix[len(prefix)-i:]) {
+				prefix = prefix[:len(prefix)-i]
+			}
+			break
+		}
+	}
+	if !utf8.Valid(prefix) {
+		return Windows1252
+	}
+	return UTF8
+}
+
+// NewReader returns a reader that transcodes the input from the named encoding to UTF-8.
+// A leading byte order mark is removed.
+//
+// When the encoding is Auto, the encoding is detected from the start of the input.
+// Input detected as UTF-8 is decoded leniently: any byte that is not part of a valid
+// UTF-8 sequence is decoded as Windows-1252.
+func NewReader(r io.Reader, encoding string) (io.Reader, error) {
+	name, err := Name(encoding)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+
+	lenient := false
+	if name == Auto {
+		prefix, err := br.Peek(detectLength)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+		name, lenient = Detect(prefix), true
+	}
+
+	switch name {
+	case UTF8:
+		if err := skipBOM(br, bomUTF8); err != nil {
+			return nil, err
+		}
+		if !lenient {
+			// the lexer reports invalid characters with their position
+			return br, nil
+		}
+		return &decoder{r: br, decode: decodeUTF8}, nil
+	case UTF16LE:
+		if err := skipBOM(br, bomUTF16LE); err != nil {
+			return nil, err
+		}
+		return &decoder{r: br, decode: decodeUTF16LE}, nil
+	case UTF16BE:
+		if err := skipBOM(br, bomUTF16BE); err != nil {
+			return nil, err
+		}
+		return &decoder{r: br, decode: decodeUTF16BE}, nil
+	case Windows1252:
+		return &decoder{r: br, decode: decodeWindows1252}, nil
+	case Latin1:
+		return &decoder{r: br, decode: decodeLatin1}, nil
+	}
+	return nil, fmt.Errorf("charset: unsupported encoding %q", name)
+}
+
+// detectLength is the number of bytes of input used to detect the encoding.
+const detectLength = 4096
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// skipBOM consumes the byte order mark if it is present.
+func skipBOM(r *bufio.Reader, bom []byte) error {
+	prefix, err := r.Peek(len(bom))
+	if err != nil && err != io.EOF {
+		return err
+	} else if bytes.Equal(prefix, bom) {
+		_, err = r.Discard(len(bom))
+		return err
+	}
+	return nil
+}
+
+// decoder translates the input one character at a time.
+type decoder struct {
+	r      *bufio.Reader
+	decode func(r *bufio.Reader) (rune, error)
+	out    []byte // transcoded text not yet returned
+	err    error
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	var buf [utf8.UTFMax]byte
+	for len(d.out) < len(p) && d.err == nil {
+		var ch rune
+		if ch, d.err = d.decode(d.r); d.err == nil {
+			w := utf8.EncodeRune(buf[:], ch)
+			d.out = append(d.out, buf[:w]...)
+		}
+	}
+	n := copy(p, d.out)
+	if d.out = d.out[n:]; len(d.out) == 0 {
+		d.out = nil
+		if n == 0 {
+			return 0, d.err
+		}
+	}
+	return n, nil
+}
+
+// decodeUTF8 returns the next character, decoding invalid bytes as Windows-1252.
+func decodeUTF8(r *bufio.Reader) (rune, error) {
+	ch, w, err := r.ReadRune()
+	if err != nil {
+		return 0, err
+	} else if ch != utf8.RuneError || w != 1 {
+		return ch, nil
+	}
+	if err := r.UnreadRune(); err != nil {
+		return 0, err
+	}
+	return decodeWindows1252(r)
+}
+
+func decodeUTF16LE(r *bufio.Reader) (rune, error) {
+	return decodeUTF16(r, func(b []byte) rune { return rune(b[0]) | rune(b[1])<<8 })
+}
+
+func decodeUTF16BE(r *bufio.Reader) (rune, error) {
+	return decodeUTF16(r, func(b []byte) rune { return rune(b[0])<<8 | rune(b[1]) })
+}
+
+// decodeUTF16 returns the next character, combining surrogate pairs.
+// An unpaired surrogate is returned as utf8.RuneError.
+func decodeUTF16(r *bufio.Reader, unit func([]byte) rune) (rune, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	hi := unit(b[:])
+	if hi < 0xD800 || 0xDFFF < hi {
+		return hi, nil
+	} else if hi > 0xDBFF {
+		return utf8.RuneError, nil
+	}
+	next, err := r.Peek(2)
+	if err != nil && err != io.EOF {
+		return 0, err
+	} else if len(next) < 2 {
+		return utf8.RuneError, nil
+	}
+	lo := unit(next)
+	if lo < 0xDC00 || 0xDFFF < lo {
+		return utf8.RuneError, nil
+	}
+	_, _ = r.Discard(2)
+	return 0x10000 + (hi-0xD800)<<10 + (lo - 0xDC00), nil
+}
+
+func decodeLatin1(r *bufio.Reader) (rune, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	return rune(b), nil
+}
+
+func decodeWindows1252(r *bufio.Reader) (rune, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	} else if 0x80 <= b && b <= 0x9F {
+		return windows1252[b-0x80], nil
+	}
+	return rune(b), nil
+}
+
+// windows1252 maps the bytes 0x80 through 0x9f to Unicode.
+// The five undefined bytes are mapped to the matching C1 control characters.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
@@ -0,0 +1,147 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package charset
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	for _, tc := range []struct {
+		encoding string
+		want     string
+	}{
+		{"", Auto},
+		{"AUTO", Auto},
+		{"utf8", UTF8},
+		{"UTF-8", UTF8},
+		{"utf-16", UTF16LE},
+		{"UTF_16BE", UTF16BE},
+		{"cp1252", Windows1252},
+		{"ansi", Windows1252},
+		{"latin1", Latin1},
+		{"ISO-8859-1", Latin1},
+	} {
+		if got, err := Name(tc.encoding); err != nil || got != tc.want {
+			t.Errorf("Name(%q) = %q, %v, want %q", tc.encoding, got, err, tc.want)
+		}
+	}
+	if _, err := Name("ebcdic"); err == nil {
+		t.Errorf("Name(%q): want an error", "ebcdic")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		prefix string
+		want   string
+	}{
+		{"empty", "", UTF8},
+		{"ascii", "!Type:Bank\n", UTF8},
+		{"utf-8", "PCafé\n", UTF8},
+		{"utf-8 bom", "\xEF\xBB\xBF!Type:Bank\n", UTF8},
+		{"utf-16le bom", "\xFF\xFE!\x00", UTF16LE},
+		{"utf-16be bom", "\xFE\xFF\x00!", UTF16BE},
+		{"utf-16le without bom", "!\x00T\x00y\x00p\x00e\x00", UTF16LE},
+		{"utf-16be without bom", "\x00!\x00T\x00y\x00p\x00e", UTF16BE},
+		{"windows-1252", "PCaf\xE9\n", Windows1252},
+		{"windows-1252 euro", "T\x8010.00\n", Windows1252},
+		{"utf-8 cut off", "PCaf\xC3", UTF8},
+	} {
+		if got := Detect([]byte(tc.prefix)); got != tc.want {
+			t.Errorf("%s: Detect(%q) = %q, want %q", tc.name, tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		encoding string
+		input    string
+		want     string
+	}{
+		{"utf-8", UTF8, "PCafé\n", "PCafé\n"},
+		{"utf-8 bom", UTF8, "\xEF\xBB\xBFPCafé\n", "PCafé\n"},
+		{"utf-8 keeps invalid bytes", UTF8, "PCaf\xE9\n", "PCaf\xE9\n"},
+		{"auto utf-8 bom", Auto, "\xEF\xBB\xBFPCafé\n", "PCafé\n"},
+		{"auto mixed is windows-1252", Auto, "PCafé \x80\n", "PCafÃ© €\n"},
+		{"utf-16le", UTF16LE, "\xFF\xFEP\x00\xAC\x20\n\x00", "P€\n"},
+		{"utf-16be", UTF16BE, "\xFE\xFF\x00P\x20\xAC\x00\n", "P€\n"},
+		{"auto utf-16le", Auto, "\xFF\xFEP\x00\xE9\x00\n\x00", "Pé\n"},
+		{"auto utf-16be without bom", Auto, "\x00P\x00\xE9\x00\n", "Pé\n"},
+		{"utf-16le surrogate pair", UTF16LE, "P\x00\x3D\xD8\x00\xDE", "P\U0001F600"},
+		{"utf-16le unpaired surrogate", UTF16LE, "\x3D\xD8P\x00", "�P"},
+		{"windows-1252", Windows1252, "PCaf\xE9 \x80\x96\n", "PCafé €–\n"},
+		{"auto windows-1252", Auto, "T\x8010.00\nPNi\xF1o\n", "T€10.00\nPNiño\n"},
+		{"latin-1", Latin1, "PCaf\xE9 \x80\n", "PCafé \u0080\n"},
+	} {
+		r, err := NewReader(strings.NewReader(tc.input), tc.encoding)
+		if err != nil {
+			t.Errorf("%s: NewReader: %v", tc.name, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("%s: ReadAll: %v", tc.name, err)
+		} else if string(got) != tc.want {
+			t.Errorf("%s: read %q, want %q", tc.name, got, tc.want)
+		}
+	}
+	// an invalid byte after the prefix used for detection is read as Windows-1252
+	valid := strings.Repeat("PCafé\n", detectLength)
+	r, err := NewReader(strings.NewReader(valid+"T\x8010.00\n"), Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadAll(r); err != nil {
+		t.Errorf("auto utf-8 is lenient: ReadAll: %v", err)
+	} else if !strings.HasPrefix(string(got), valid) || string(got[len(valid):]) != "T€10.00\n" {
+		t.Errorf("auto utf-8 is lenient: read %q after the prefix, want %q", strings.TrimPrefix(string(got), valid), "T€10.00\n")
+	}
+
+	if _, err := NewReader(strings.NewReader(""), "ebcdic"); err == nil {
+		t.Errorf("NewReader(%q): want an error", "ebcdic")
+	}
+}
+
+func TestNewReaderLongInput(t *testing.T) {
+	// the output is larger than the input, and larger than the buffers used to read it
+	input := strings.Repeat("PCaf\xE9 \x80\n", 2000)
+	want := strings.Repeat("PCafé €\n", 2000)
+	r, err := NewReader(strings.NewReader(input), Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(got) != want {
+		t.Errorf("read %d bytes, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,208 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/mdhender/qif2json/export"
+	"github.com/mdhender/qif2json/qif"
+	"github.com/peterbourgon/ff/v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func main() {
+	fs := flag.NewFlagSet("json2qif", flag.ExitOnError)
+	var (
+		accts = fs.String("accounts", "", "accounts file written by qif2json")
+		cats  = fs.String("categories", "", "categories file written by qif2json, flat or nested")
+		trans = fs.String("transactions", "", "transactions file written by qif2json")
+		doc   = fs.String("document", "", "combined document written by qif2json -output")
+		order = fs.String("date-order", "mdy", "order of the month and day in the dates written (mdy or dmy)")
+		outp  = fs.String("output", "", "QIF file to write")
+		_     = fs.String("config", "", "config file (optional)")
+	)
+
+	if err := ff.Parse(fs, os.Args[1:], ff.WithEnvVarPrefix("JSON2QIF"), ff.WithConfigFileFlag("config"), ff.WithConfigFileParser(ff.PlainParser)); err != nil {
+		fmt.Printf("%+v\n", err)
+		os.Exit(2)
+	}
+
+	if *outp == "" {
+		fmt.Printf("please provide the name of the QIF file to write\n")
+		os.Exit(2)
+	} else if *accts == "" && *cats == "" && *trans == "" && *doc == "" {
+		fmt.Printf("please provide at least one JSON file to translate\n")
+		os.Exit(2)
+	}
+	// the flags mean the opposite of the qif2json flags, so they have their own
+	// prefix, and the QIF file must not overwrite one of the JSON files
+	for _, input := range []string{*doc, *accts, *cats, *trans} {
+		if input != "" && sameFile(input, *outp) {
+			fmt.Printf("the output %q is one of the JSON files to translate\n", *outp)
+			os.Exit(2)
+		}
+	}
+	if *doc != "" {
+		fmt.Printf("%-30s == %q\n", "JSON2QIF_DOCUMENT", *doc)
+	}
+	if *accts != "" {
+		fmt.Printf("%-30s == %q\n", "JSON2QIF_ACCOUNTS", *accts)
+	}
+	if *cats != "" {
+		fmt.Printf("%-30s == %q\n", "JSON2QIF_CATEGORIES", *cats)
+	}
+	if *trans != "" {
+		fmt.Printf("%-30s == %q\n", "JSON2QIF_TRANSACTIONS", *trans)
+	}
+	fmt.Printf("%-30s == %q\n", "JSON2QIF_DATE_ORDER", *order)
+	fmt.Printf("%-30s == %q\n", "JSON2QIF_OUTPUT", *outp)
+
+	var opts qif.WriteOptions
+	switch *order {
+	case "mdy":
+	case "dmy":
+		opts.DayFirst = true
+	default:
+		fmt.Printf("date-order must be mdy or dmy\n")
+		os.Exit(2)
+	}
+
+	if err := run(*doc, *accts, *cats, *trans, *outp, opts); err != nil {
+		fmt.Printf("%+v\n", err)
+		os.Exit(2)
+	}
+}
+
+func run(document, accounts, categories, transactions, output string, opts qif.WriteOptions) error {
+	started := time.Now()
+
+	// the separate files replace the sections of the combined document
+	var doc export.Document
+	if document != "" {
+		if err := readJSON(document, &doc); err != nil {
+			return err
+		} else if doc.Version > export.DocumentVersion {
+			return fmt.Errorf("%s: unsupported document version %d", document, doc.Version)
+		}
+	}
+	if accounts != "" {
+		var data struct {
+			Accounts []export.Account `json:"accounts"`
+		}
+		if err := readJSON(accounts, &data); err != nil {
+			return err
+		}
+		doc.Accounts = data.Accounts
+	}
+	if categories != "" {
+		// a nested category has a path, and is a superset of a flat category
+		var data struct {
+			Categories []*export.CategoryNode `json:"categories"`
+		}
+		if err := readJSON(categories, &data); err != nil {
+			return err
+		}
+		doc.Categories = export.FlattenCategoryTree(data.Categories)
+	}
+	if transactions != "" {
+		var data struct {
+			Transactions []export.Transaction `json:"transactions"`
+		}
+		if err := readJSON(transactions, &data); err != nil {
+			return err
+		}
+		doc.Transactions = data.Transactions
+	}
+
+	r, err := doc.Records()
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := qif.Write(fd, r, opts); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("wrote    %8d accounts\n", len(r.Accounts.Records))
+	fmt.Printf("wrote    %8d categories\n", len(r.Categories.Records))
+	fmt.Printf("wrote    %8d classes\n", len(r.Classes.Records))
+	fmt.Printf("wrote    %8d investments\n", len(r.Investments))
+	fmt.Printf("wrote    %8d memorized\n", len(r.Memorized))
+	fmt.Printf("wrote    %8d prices\n", len(r.Prices))
+	fmt.Printf("wrote    %8d securities\n", len(r.Securities.Records))
+	fmt.Printf("wrote    %8d tags\n", len(r.Tags.Records))
+	fmt.Printf("wrote    %8d transactions\n", len(r.Transactions))
+	fmt.Printf("wrote QIF to %q in %v\n", output, time.Now().Sub(started))
+
+	return nil
+}
+
+// sameFile returns true if the two names refer to the same file.
+func sameFile(a, b string) bool {
+	if absA, err := filepath.Abs(a); err == nil {
+		if absB, err := filepath.Abs(b); err == nil && absA == absB {
+			return true
+		}
+	}
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// readJSON reads the file into data.
+// Fields that aren't in the schema are an error, since they
+// usually mean the file isn't the one that was expected.
+func readJSON(name string, data interface{}) error {
+	buf, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(data); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -25,231 +25,692 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
-	"github.com/mdhender/qif2json/buffer"
+	"github.com/mdhender/qif2json/charset"
+	"github.com/mdhender/qif2json/date"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/export"
+	"github.com/mdhender/qif2json/gnucash"
+	"github.com/mdhender/qif2json/journal"
+	"github.com/mdhender/qif2json/ofx"
 	"github.com/mdhender/qif2json/reader"
 	"github.com/mdhender/qif2json/transformer"
 	"github.com/peterbourgon/ff/v3"
-	"io/ioutil"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
+	"unicode"
 )
 
+// version is the version of qif2json written to the combined document.
+// It can be set at build time with -ldflags "-X main.version=...".
+var version = "0.2.0"
+
+// console is where the settings and progress are reported.
+// It is stderr when the NDJSON output is written to stdout.
+var console io.Writer = os.Stdout
+
 func main() {
 	fs := flag.NewFlagSet("my-program", flag.ExitOnError)
 	var (
 		input = fs.String("input", "", "QIF file to translate")
+		enc   = fs.String("encoding", charset.Auto, "character set of the QIF file (auto, utf-8, utf-16le, utf-16be, windows-1252, iso-8859-1)")
+		order = fs.String("date-order", "mdy", "order of the month and day in dates (mdy or dmy)")
+		pivot = fs.Int("date-pivot", date.DefaultPivot, "two-digit years less than the pivot are in the 21st century")
+		lays  = fs.String("date-layouts", "", "comma separated list of date layouts (optional)")
+		nums  = fs.String("number-locale", "us", "separators used in numbers (us, eu, ch, or auto to detect them)")
+		recov = fs.Bool("recover", false, "report every malformed record and keep the good ones")
 		accts = fs.String("accounts", "", "file to write accounts to")
 		cats  = fs.String("categories", "", "file to write categories to")
+		nest  = fs.Bool("categories-nested", false, "write categories as a tree of subcategories")
+		clss  = fs.String("classes", "", "file to write classes to")
+		memo  = fs.String("memorized", "", "file to write memorized transactions to")
+		price = fs.String("prices", "", "file to write the price history to")
+		secs  = fs.String("securities", "", "file to write securities to")
+		tags  = fs.String("tags", "", "file to write tags to")
 		trans = fs.String("transactions", "", "file to write transactions to")
+		csvf  = fs.String("csv", "", "file to write transactions to as CSV")
+		csvl  = fs.String("csv-layout", string(export.PerSplit), "one CSV row per transaction or per split (transaction or split)")
+		csvc  = fs.String("csv-columns", "", "comma separated list of CSV columns (optional)")
+		csvd  = fs.String("csv-delimiter", ",", "CSV field delimiter (a single character, or tab)")
+		cmap  = fs.String("category-map", "", "mapping file to rename categories and classes with (optional)")
+		crept = fs.String("category-report", "", "file to write the categories and classes that aren't mapped to")
+		cstr  = fs.Bool("category-strict", false, "fail if any category or class isn't mapped")
+		prule = fs.String("payee-rules", "", "rules file to normalize the payees of the transactions, memorized transactions and investments with (optional)")
+		prept = fs.String("payee-report", "", "file to write the original payee and the rule applied for each record to")
+		posts = fs.String("postings", "", "file to write the transactions to as balanced double-entry postings")
+		recon = fs.String("reconcile", "", "file to write the running balances and statement reconciliation report to")
+		xfers = fs.String("transfers", "", "file to write the matched transfers and unmatched transfer halves to")
+		xdays = fs.Int("transfer-days", 3, "number of days apart the two halves of a transfer may be")
+		xcoll = fs.Bool("collapse-transfers", false, "write each matched transfer to the CSV file once")
+		ofxf  = fs.String("ofx", "", "file to write every account to as a single OFX statement bundle")
+		ofxd  = fs.String("ofx-dir", "", "directory to write one OFX statement per account to")
+		ofxb  = fs.String("ofx-bank-id", "", "BANKID for the OFX bank statements (optional)")
+		ofxc  = fs.String("ofx-currency", "USD", "currency of the OFX statements")
+		ldgr  = fs.String("ledger", "", "file to write the transactions to as a ledger journal")
+		hldgr = fs.String("hledger", "", "file to write the transactions to as an hledger journal")
+		bean  = fs.String("beancount", "", "file to write the transactions to as a beancount journal")
+		jcur  = fs.String("journal-currency", "USD", "commodity of the amounts in the journals")
+		gnc   = fs.String("gnucash", "", "file to write a GnuCash XML book to")
+		gncz  = fs.Bool("gnucash-gzip", false, "compress the GnuCash book, as GnuCash does")
+		sqlf  = fs.String("sql", "", "file to write an SQL script that creates and loads the tables to")
+		sqld  = fs.String("sql-dialect", string(export.SQLite), "dialect of the SQL script (sqlite or postgres)")
+		outp  = fs.String("output", "", "file to write every section to as a single document")
+		ndjs  = fs.String("ndjson", "", "file to stream every record to as newline-delimited JSON (- for stdout)")
 		_     = fs.String("config", "", "config file (optional)")
 	)
 
 	if err := ff.Parse(fs, os.Args[1:], ff.WithEnvVarPrefix("QIFXLAT"), ff.WithConfigFileFlag("config"), ff.WithConfigFileParser(ff.PlainParser)); err != nil {
-		fmt.Printf("%+v\n", err)
+		fmt.Fprintf(console, "%+v\n", err)
 		os.Exit(2)
 	}
 
+	if *ndjs == "-" {
+		console = os.Stderr
+	}
 	if *input == "" {
-		fmt.Printf("please provide the name of the QIF file to translate\n")
+		fmt.Fprintf(console, "please provide the name of the QIF file to translate\n")
 		os.Exit(2)
 	}
-	fmt.Printf("%-30s == %q\n", "QIFXLAT_INPUT", *input)
+	fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_INPUT", *input)
+	fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_ENCODING", *enc)
+	fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_DATE_ORDER", *order)
+	fmt.Fprintf(console, "%-30s == %d\n", "QIFXLAT_DATE_PIVOT", *pivot)
+	if *lays != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_DATE_LAYOUTS", *lays)
+	}
+	fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_NUMBER_LOCALE", *nums)
+	if *recov {
+		fmt.Fprintf(console, "%-30s == %v\n", "QIFXLAT_RECOVER", *recov)
+	}
 	if *accts != "" {
-		fmt.Printf("%-30s == %q\n", "QIFXLAT_ACCOUNTS", *accts)
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_ACCOUNTS", *accts)
 	}
 	if *cats != "" {
-		fmt.Printf("%-30s == %q\n", "QIFXLAT_CATEGORIES", *cats)
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CATEGORIES", *cats)
+	}
+	if *nest {
+		fmt.Fprintf(console, "%-30s == %v\n", "QIFXLAT_CATEGORIES_NESTED", *nest)
+	}
+	if *clss != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CLASSES", *clss)
+	}
+	if *memo != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_MEMORIZED", *memo)
+	}
+	if *price != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_PRICES", *price)
+	}
+	if *secs != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_SECURITIES", *secs)
+	}
+	if *tags != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_TAGS", *tags)
 	}
 	if *trans != "" {
-		fmt.Printf("%-30s == %q\n", "QIFXLAT_TRANSACTIONS", *trans)
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_TRANSACTIONS", *trans)
+	}
+	if *csvf != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CSV", *csvf)
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CSV_LAYOUT", *csvl)
+		if *csvc != "" {
+			fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CSV_COLUMNS", *csvc)
+		}
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CSV_DELIMITER", *csvd)
+	}
+	if *cmap != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CATEGORY_MAP", *cmap)
+		fmt.Fprintf(console, "%-30s == %v\n", "QIFXLAT_CATEGORY_STRICT", *cstr)
+	}
+	if *crept != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_CATEGORY_REPORT", *crept)
+	}
+	if *prule != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_PAYEE_RULES", *prule)
+	}
+	if *prept != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_PAYEE_REPORT", *prept)
+	}
+	if *posts != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_POSTINGS", *posts)
+	}
+	if *recon != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_RECONCILE", *recon)
+	}
+	if *xfers != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_TRANSFERS", *xfers)
+	}
+	if *xfers != "" || *csvf != "" {
+		fmt.Fprintf(console, "%-30s == %d\n", "QIFXLAT_TRANSFER_DAYS", *xdays)
+	}
+	if *xcoll {
+		fmt.Fprintf(console, "%-30s == %v\n", "QIFXLAT_COLLAPSE_TRANSFERS", *xcoll)
+	}
+	if *ofxf != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_OFX", *ofxf)
+	}
+	if *ofxd != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_OFX_DIR", *ofxd)
+	}
+	if *ofxf != "" || *ofxd != "" {
+		if *ofxb != "" {
+			fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_OFX_BANK_ID", *ofxb)
+		}
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_OFX_CURRENCY", *ofxc)
+	}
+	if *ldgr != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_LEDGER", *ldgr)
+	}
+	if *hldgr != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_HLEDGER", *hldgr)
+	}
+	if *bean != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_BEANCOUNT", *bean)
+	}
+	if *ldgr != "" || *hldgr != "" || *bean != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_JOURNAL_CURRENCY", *jcur)
+	}
+	if *gnc != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_GNUCASH", *gnc)
+		fmt.Fprintf(console, "%-30s == %v\n", "QIFXLAT_GNUCASH_GZIP", *gncz)
+	}
+	if *sqlf != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_SQL", *sqlf)
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_SQL_DIALECT", *sqld)
+	}
+	if *outp != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_OUTPUT", *outp)
+	}
+	if *ndjs != "" {
+		fmt.Fprintf(console, "%-30s == %q\n", "QIFXLAT_NDJSON", *ndjs)
+	}
+
+	grammar := date.Grammar{Pivot: *pivot}
+	switch *order {
+	case "mdy":
+	case "dmy":
+		grammar.DayFirst = true
+	default:
+		fmt.Fprintf(console, "date-order must be mdy or dmy\n")
+		os.Exit(2)
+	}
+	if *pivot < 1 || *pivot > 100 {
+		fmt.Fprintf(console, "date-pivot must be between 1 and 100\n")
+		os.Exit(2)
+	}
+	if *lays != "" {
+		grammar.Layouts = strings.Split(*lays, ",")
+	}
+	opts := reader.Options{Dates: grammar, Recover: *recov}
+	if *nums == "auto" {
+		opts.Numbers = &decimal.Auto{}
+	} else if locale, err := decimal.LocaleByName(*nums); err != nil {
+		fmt.Fprintf(console, "%+v\n", err)
+		os.Exit(2)
+	} else {
+		opts.Numbers = locale
 	}
 
-	if err := run(*input, *accts, *cats, *trans); err != nil {
-		fmt.Printf("%+v\n", err)
+	csvOpts := export.CSVOptions{Layout: export.Layout(*csvl)}
+	if *csvc != "" {
+		csvOpts.Columns = strings.Split(*csvc, ",")
+	}
+	if *csvd == "tab" || *csvd == "\\t" {
+		csvOpts.Delimiter = '\t'
+	} else if delimiter := []rune(*csvd); len(delimiter) == 1 {
+		csvOpts.Delimiter = delimiter[0]
+	} else {
+		fmt.Fprintf(console, "csv-delimiter must be a single character\n")
+		os.Exit(2)
+	}
+
+	var categoryMap *transformer.CategoryMap
+	if *cmap != "" {
+		var err error
+		if categoryMap, err = readCategoryMap(*cmap); err != nil {
+			fmt.Fprintf(console, "%+v\n", err)
+			os.Exit(2)
+		}
+	} else if *crept != "" || *cstr {
+		fmt.Fprintf(console, "category-report and category-strict need category-map\n")
 		os.Exit(2)
 	}
+	var payeeRules *transformer.PayeeRules
+	if *prule != "" {
+		var err error
+		if payeeRules, err = readPayeeRules(*prule); err != nil {
+			fmt.Fprintf(console, "%+v\n", err)
+			os.Exit(2)
+		}
+	} else if *prept != "" {
+		fmt.Fprintf(console, "payee-report needs payee-rules\n")
+		os.Exit(2)
+	}
+	if *xdays < 0 {
+		fmt.Fprintf(console, "transfer-days can't be negative\n")
+		os.Exit(2)
+	}
+
+	out := outputs{
+		accounts:         *accts,
+		categories:       *cats,
+		categoriesNested: *nest,
+		classes:          *clss,
+		memorized:        *memo,
+		prices:           *price,
+		securities:       *secs,
+		tags:             *tags,
+		transactions:     *trans,
+		document:         *outp,
+		csv:              *csvf,
+		csvOptions:       csvOpts,
+		categoryMap:      categoryMap,
+		categoryReport:   *crept,
+		categoryStrict:   *cstr,
+		payeeRules:       payeeRules,
+		payeeReport:      *prept,
+		postings:         *posts,
+		reconcile:        *recon,
+		transfers:        *xfers,
+		transferOptions:  transformer.MatchOptions{Days: *xdays},
+		collapse:         *xcoll,
+		ofx:              *ofxf,
+		ofxDir:           *ofxd,
+		ofxOptions:       ofx.Options{BankID: *ofxb, Currency: *ofxc},
+		ledger:           *ldgr,
+		hledger:          *hldgr,
+		beancount:        *bean,
+		journalOptions:   journal.Options{Currency: *jcur},
+		gnucash:          *gnc,
+		gnucashOptions:   gnucash.Options{Compress: *gncz},
+		sql:              *sqlf,
+		sqlOptions:       export.SQLOptions{Dialect: export.Dialect(*sqld)},
+	}
+	if *ndjs != "" {
+		// the other outputs need the whole file in memory, which defeats the purpose of streaming
+		if out.any() || out.categoryMap != nil || out.payeeRules != nil {
+			fmt.Fprintf(console, "ndjson can't be combined with the other outputs\n")
+			os.Exit(2)
+		}
+		if err := stream(*input, *enc, opts, *ndjs); err != nil {
+			fmt.Fprintf(console, "%+v\n", err)
+			os.Exit(2)
+		}
+		return
+	}
+	if err := run(*input, *enc, opts, out); err != nil {
+		fmt.Fprintf(console, "%+v\n", err)
+		os.Exit(2)
+	}
+}
+
+// outputs are the names of the files to write.
+// An empty name means the file isn't written.
+type outputs struct {
+	accounts         string
+	categories       string
+	categoriesNested bool
+	classes          string
+	memorized        string
+	prices           string
+	securities       string
+	tags             string
+	transactions     string
+	document         string
+	csv              string
+	csvOptions       export.CSVOptions
+	categoryMap      *transformer.CategoryMap
+	categoryReport   string
+	categoryStrict   bool // fail if a category or class isn't mapped
+	payeeRules       *transformer.PayeeRules
+	payeeReport      string
+	postings         string
+	reconcile        string
+	transfers        string
+	transferOptions  transformer.MatchOptions
+	collapse         bool // write each transfer to the CSV file once
+	ofx              string
+	ofxDir           string // one file per account
+	ofxOptions       ofx.Options
+	ledger           string
+	hledger          string
+	beancount        string
+	journalOptions   journal.Options
+	gnucash          string
+	gnucashOptions   gnucash.Options
+	sql              string
+	sqlOptions       export.SQLOptions
+}
+
+// any returns true if any of the files are to be written.
+func (out outputs) any() bool {
+	for _, name := range []string{out.accounts, out.categories, out.classes, out.memorized, out.prices, out.securities, out.tags, out.transactions, out.document, out.csv, out.categoryReport, out.payeeReport, out.postings, out.reconcile, out.transfers, out.ofx, out.ofxDir, out.ledger, out.hledger, out.beancount, out.gnucash, out.sql} {
+		if name != "" {
+			return true
+		}
+	}
+	return false
 }
 
-func run(name, accounts, categories, transactions string) error {
+func run(name, encoding string, opts reader.Options, out outputs) error {
 	started := time.Now()
 
-	input, err := ioutil.ReadFile(name)
+	input, err := os.Open(name)
 	if err != nil {
 		return err
 	}
-	buf, err := buffer.NewBuffer(input)
+	defer input.Close()
+	// hash the bytes of the file as they are read for the document metadata
+	hash := sha256.New()
+	text, err := charset.NewReader(io.TeeReader(input, hash), encoding)
 	if err != nil {
 		return err
 	}
-	r, err := reader.Read(buf)
+	if auto, ok := opts.Numbers.(*decimal.Auto); ok {
+		if text, err = prescan(text, auto); err != nil {
+			return err
+		}
+	}
+	r, err := reader.ReadFrom(text, opts)
 	if err != nil {
 		return err
 	}
-	if accounts != "" {
-		type Account struct {
-			Type                 string `json:"type"`
-			Name                 string `json:"name"`
-			CreditLimit          string `json:"credit_limit,omitempty"`
-			Description          string `json:"descr,omitempty"`
-			StatementBalance     string `json:"balance,omitempty"`
-			StatementBalanceDate string `json:"statement_date,omitempty"`
-		}
-		var data struct {
-			Accounts []Account `json:"accounts"`
-		}
-		for _, account := range r.Accounts.Records {
-			var typ string
-			switch account.Type {
-			case "Bank":
-				typ = "bank"
-			case "CCard":
-				typ = "creditCard"
-			case "Cash":
-				typ = "cash"
-			case "Oth A":
-				typ = "asset"
-			case "Oth L":
-				typ = "liability"
-			case "Port":
-				typ = "brokerage"
-			case "401(k)/403(b)":
-				typ = "retirement"
-			default:
-				panic(fmt.Sprintf("assert(account.type != %q)", account.Type))
-			}
-			data.Accounts = append(data.Accounts, Account{
-				Type:                 typ,
-				Name:                 account.Name,
-				CreditLimit:          account.CreditLimit,
-				Description:          account.Description,
-				StatementBalance:     account.StatementBalance,
-				StatementBalanceDate: account.StatementBalanceDate,
-			})
-		}
-		if err := writeJSON(accounts, data); err != nil {
+	for _, diagnostic := range r.Diagnostics {
+		fmt.Fprintf(console, "%s: %s\n", name, diagnostic)
+	}
+
+	// the categories are renamed and the payees normalized before anything is written
+	var categoryReport *transformer.CategoryReport
+	if out.categoryMap != nil {
+		if categoryReport, err = transformer.RemapCategories(r, out.categoryMap, out.categoryStrict); err != nil {
 			return err
 		}
+		fmt.Fprintf(console, "unmapped %8d categories\n", len(categoryReport.Categories))
+		fmt.Fprintf(console, "unmapped %8d classes\n", len(categoryReport.Classes))
+	}
+	var payeeChanges []*transformer.PayeeChange
+	if out.payeeRules != nil {
+		payeeChanges = transformer.NormalizePayees(r, out.payeeRules)
 	}
 
-	if categories != "" {
-		type Category struct {
-			Name        string `json:"name"`
-			Description string `json:"descr,omitempty"`
-			Income      bool   `json:"income,omitempty"`
-			TaxRelated  bool   `json:"tax_related,omitempty"`
-			TaxSchedule string `json:"tax_schedule,omitempty"`
-		}
-		var data struct {
-			Categories []Category `json:"categories"`
-		}
-		for _, category := range r.Categories.Records {
-			data.Categories = append(data.Categories, Category{
-				Name:        category.Name,
-				Description: category.Description,
-				Income:      category.IsIncome,
-				TaxRelated:  category.IsTaxRelated,
-				TaxSchedule: category.TaxSchedule,
-			})
-		}
-		if err := writeJSON(categories, data); err != nil {
-			return err
+	// the journals and the book link the holdings of a security to its prices
+	if out.ledger != "" || out.hledger != "" || out.beancount != "" || out.gnucash != "" {
+		for _, security := range transformer.UnresolvedSecurities(r) {
+			fmt.Fprintf(console, "%s: security %q isn't in the security list, so its holdings and prices aren't linked\n", name, security)
 		}
 	}
 
-	if transactions != "" {
-		normalized := transformer.NormalizeSplits(r.Transactions)
-
-		type Split struct {
-			Line     int    `json:"line,omitempty"`
-			Account  string `json:"account,omitempty"`
-			Amount   string `json:"amount,omitempty"`
-			Category string `json:"category,omitempty"`
-			Memo     string `json:"memo,omitempty"`
-		}
-		type Transaction struct {
-			Line          int     `json:"line,omitempty"`
-			Type          string  `json:"type,omitempty"`
-			Date          string  `json:"date,omitempty"`
-			Account       string  `json:"account,omitempty"`
-			ToAccount     string  `json:"to_account,omitempty"`
-			Amount        string  `json:"amount,omitempty"`
-			Category      string  `json:"category,omitempty"`
-			ClearedStatus string  `json:"cleared_status,omitempty"`
-			Memo          string  `json:"memo,omitempty"`
-			Payee         string  `json:"payee,omitempty"`
-			RefNo         string  `json:"ref_no,omitempty"`
-			Split         []Split `json:"lines,omitempty"`
-		}
-		var data struct {
-			Transactions []Transaction `json:"transactions"`
-		}
-		for _, transaction := range normalized {
-			xact := Transaction{
-				Line:          transaction.Line,
-				Account:       transaction.Account,
-				ClearedStatus: transaction.ClearedStatus,
-				Date:          transaction.Date,
-				Memo:          transaction.Memo,
-				Payee:         transaction.Payee,
-				RefNo:         transaction.RefNo,
-			}
-			for _, line := range transaction.Split {
-				split := Split{
-					Line:     line.Line,
-					Account:  line.Account,
-					Amount:   line.Amount,
-					Category: line.Category,
-					Memo:     line.Memo,
+	// the transfers are matched for the CSV file too, so it has the transfer IDs
+	transactions := transformer.NormalizeSplits(r.Transactions)
+	transfers := transformer.MatchTransfers(transactions, out.transferOptions)
+	if out.collapse {
+		transactions = transformer.CollapseTransfers(transactions, transfers)
+	}
+
+	for _, output := range []struct {
+		name   string
+		encode func(w io.Writer) error
+	}{
+		{out.accounts, func(w io.Writer) error { return export.EncodeAccounts(w, r.Accounts) }},
+		{out.categories, func(w io.Writer) error { return export.EncodeCategories(w, r.Categories, out.categoriesNested) }},
+		{out.classes, func(w io.Writer) error { return export.EncodeClasses(w, r.Classes) }},
+		{out.memorized, func(w io.Writer) error { return export.EncodeMemorized(w, r.Memorized) }},
+		{out.prices, func(w io.Writer) error { return export.EncodePrices(w, r.Prices) }},
+		{out.securities, func(w io.Writer) error { return export.EncodeSecurities(w, r.Securities) }},
+		{out.tags, func(w io.Writer) error { return export.EncodeTags(w, r.Tags) }},
+		{out.transactions, func(w io.Writer) error { return export.EncodeTransactions(w, r) }},
+		{out.csv, func(w io.Writer) error {
+			return export.WriteCSV(w, transactions, out.csvOptions)
+		}},
+		{out.categoryReport, func(w io.Writer) error { return export.EncodeCategoryReport(w, categoryReport) }},
+		{out.payeeReport, func(w io.Writer) error { return export.EncodePayeeChanges(w, payeeChanges, out.payeeRules) }},
+		{out.postings, func(w io.Writer) error {
+			entries := transformer.Postings(transformer.NormalizeSplits(r.Transactions))
+			for _, e := range entries {
+				if e.Unbalanced {
+					fmt.Fprintf(console, "%s: %d: the splits don't add up to the amount of the transaction, which is off by %s\n", name, e.Transaction.Line, e.Difference)
 				}
-				xact.Split = append(xact.Split, split)
 			}
-			data.Transactions = append(data.Transactions, xact)
+			return export.EncodePostings(w, entries)
+		}},
+		{out.reconcile, func(w io.Writer) error {
+			return export.WriteReconciliation(w, transformer.Balances(r.Accounts, transformer.NormalizeSplits(r.Transactions)))
+		}},
+		{out.transfers, func(w io.Writer) error { return export.EncodeTransfers(w, transfers) }},
+		{out.ofx, func(w io.Writer) error { return ofx.WriteBundle(w, r, out.ofxOptions) }},
+		{out.ledger, func(w io.Writer) error { return journal.Write(w, r, journal.Ledger, out.journalOptions) }},
+		{out.hledger, func(w io.Writer) error { return journal.Write(w, r, journal.HLedger, out.journalOptions) }},
+		{out.beancount, func(w io.Writer) error { return journal.Write(w, r, journal.Beancount, out.journalOptions) }},
+		{out.gnucash, func(w io.Writer) error { return gnucash.Write(w, r, out.gnucashOptions) }},
+		{out.sql, func(w io.Writer) error { return export.WriteSQL(w, r, out.sqlOptions) }},
+		{out.document, func(w io.Writer) error {
+			doc, err := export.NewDocument(r, export.Metadata{
+				Source:      name,
+				SHA256:      hex.EncodeToString(hash.Sum(nil)),
+				ParsedAt:    started.UTC(),
+				Tool:        "qif2json",
+				ToolVersion: version,
+			})
+			if err != nil {
+				return err
+			}
+			return export.EncodeDocument(w, doc)
+		}},
+	} {
+		if output.name == "" {
+			continue
 		}
-		if err := writeJSON(transactions, data); err != nil {
+		if err := writeFile(output.name, output.encode); err != nil {
+			return err
+		}
+	}
+	if out.ofxDir != "" {
+		if err := writeStatements(out.ofxDir, r, out.ofxOptions); err != nil {
 			return err
 		}
 	}
 
 	var totalRecords int
 	if r.Accounts != nil {
-		fmt.Printf("processed %8d accounts\n", len(r.Accounts.Records))
+		fmt.Fprintf(console, "processed %8d accounts\n", len(r.Accounts.Records))
 		totalRecords += len(r.Accounts.Records)
+	} else {
+		fmt.Fprintf(console, "processed %8d accounts\n", 0)
 	}
 	if r.Categories != nil {
-		fmt.Printf("processed %8d categories\n", len(r.Categories.Records))
+		fmt.Fprintf(console, "processed %8d categories\n", len(r.Categories.Records))
 		totalRecords += len(r.Categories.Records)
+	} else {
+		fmt.Fprintf(console, "processed %8d categories\n", 0)
 	}
+	if r.Classes != nil {
+		fmt.Fprintf(console, "processed %8d classes\n", len(r.Classes.Records))
+		totalRecords += len(r.Classes.Records)
+	}
+	totalRecords += len(r.Investments)
+	fmt.Fprintf(console, "processed %8d investments\n", len(r.Investments))
 	totalRecords += len(r.Memorized)
-	fmt.Printf("processed %8d memorized\n", len(r.Memorized))
+	fmt.Fprintf(console, "processed %8d memorized\n", len(r.Memorized))
 	totalRecords += len(r.Prices)
-	fmt.Printf("processed %8d prices\n", len(r.Prices))
+	fmt.Fprintf(console, "processed %8d prices\n", len(r.Prices))
 	if r.Securities != nil {
-		fmt.Printf("processed %8d securities\n", len(r.Securities.Records))
+		fmt.Fprintf(console, "processed %8d securities\n", len(r.Securities.Records))
 		totalRecords += len(r.Securities.Records)
 	}
 	if r.Tags != nil {
-		fmt.Printf("processed %8d tags\n", len(r.Tags.Records))
+		fmt.Fprintf(console, "processed %8d tags\n", len(r.Tags.Records))
+		totalRecords += len(r.Tags.Records)
 	}
 	totalRecords += len(r.Transactions)
-	fmt.Printf("processed %8d transactions\n", len(r.Transactions))
+	fmt.Fprintf(console, "processed %8d transactions\n", len(r.Transactions))
 
 	duration := time.Now().Sub(started)
-	fmt.Printf("processed %8d records in %v\n", totalRecords, duration)
+	fmt.Fprintf(console, "processed %8d records in %v\n", totalRecords, duration)
+	if len(r.Diagnostics) != 0 {
+		fmt.Fprintf(console, "reported  %8d diagnostics\n", len(r.Diagnostics))
+	}
 
 	return nil
 }
 
-func writeJSON(name string, data interface{}) error {
-	buf, err := json.MarshalIndent(data, "", "  ")
+// prescanSize is how much of the file is scanned to detect the number locale.
+const prescanSize = 1 << 20
+
+// prescan detects the number locale from the start of the text, so the
+// numbers before the first one that settles it aren't parsed with the wrong
+// locale. Only the first prescanSize bytes are held in memory. If they don't
+// settle the locale, a number that could be in either locale is an error
+// until a later number settles it.
+func prescan(text io.Reader, auto *decimal.Auto) (io.Reader, error) {
+	br := bufio.NewReaderSize(text, prescanSize)
+	buf, err := br.Peek(prescanSize)
+	if err == bufio.ErrBufferFull || err == nil {
+		// the last line may be cut off, and a partial number could settle the wrong locale
+		buf = buf[:bytes.LastIndexByte(buf, '\n')+1]
+	} else if err != io.EOF {
+		return nil, err
+	}
+	auto.Prescan(buf)
+	return br, nil
+}
+
+// stream writes each record to the NDJSON output as it is read.
+func stream(name, encoding string, opts reader.Options, output string) error {
+	started := time.Now()
+
+	input, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	text, err := charset.NewReader(input, encoding)
 	if err != nil {
 		return err
 	}
-	if err = ioutil.WriteFile(name, buf, 0600); err != nil {
+	if auto, ok := opts.Numbers.(*decimal.Auto); ok {
+		if text, err = prescan(text, auto); err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if output != "-" {
+		fd, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer fd.Close()
+		w = fd
+	}
+	// the output isn't buffered so that each line is written as soon as its record is read
+	e, err := export.Stream(w, reader.NewDecoder(text, opts))
+	if err != nil {
 		return err
 	}
-	fmt.Printf("wrote %8d bytes to %q\n", len(buf), name)
+
+	var totalRecords int
+	for _, kind := range []string{export.KindAccount, export.KindCategory, export.KindClass, export.KindMemorized, export.KindPrice, export.KindSecurity, export.KindTag, export.KindTransaction} {
+		fmt.Fprintf(console, "streamed  %8d %s records\n", e.Counts[kind], kind)
+		totalRecords += e.Counts[kind]
+	}
+	duration := time.Now().Sub(started)
+	fmt.Fprintf(console, "streamed  %8d records in %v\n", totalRecords, duration)
+	if e.Counts[export.KindDiagnostic] != 0 {
+		fmt.Fprintf(console, "reported  %8d diagnostics\n", e.Counts[export.KindDiagnostic])
+	}
+
+	return nil
+}
+
+// readCategoryMap reads the category mapping file.
+func readCategoryMap(name string) (*transformer.CategoryMap, error) {
+	fd, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return transformer.ReadCategoryMap(fd)
+}
+
+// readPayeeRules reads the payee rules file.
+func readPayeeRules(name string) (*transformer.PayeeRules, error) {
+	fd, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return transformer.ReadPayeeRules(fd)
+}
+
+// writeStatements writes the OFX statement of each account to its own file in the directory.
+// The file is named after the account, with the characters that aren't safe in a file name replaced.
+func writeStatements(dir string, r *reader.Reader, opts ofx.Options) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	seen := make(map[string]int)
+	for _, statement := range ofx.Statements(r, opts) {
+		name := fileName(statement.Account.Name)
+		if seen[name]++; seen[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, seen[name])
+		}
+		statements := []*ofx.Statement{statement}
+		if err := writeFile(filepath.Join(dir, name+".ofx"), func(w io.Writer) error {
+			return ofx.Write(w, statements, r.Securities, opts)
+		}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// fileName returns the name with every character other than letters, digits, '-' and '.' replaced by '_'.
+func fileName(name string) string {
+	name = strings.Map(func(ch rune) rune {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '-' || ch == '.' {
+			return ch
+		}
+		return '_'
+	}, name)
+	if name == "" || strings.Trim(name, ".") == "" {
+		return "account"
+	}
+	return name
+}
+
+// writeFile creates the file and writes the encoded data to it.
+func writeFile(name string, encode func(w io.Writer) error) error {
+	fd, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	cw := &countingWriter{w: fd}
+	if err := encode(cw); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(console, "wrote %8d bytes to %q\n", cw.n, name)
+	return nil
+}
+
+// countingWriter counts the bytes written.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
@@ -0,0 +1,185 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package date parses the dates found in QIF files.
+package date
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parser converts the text of a QIF date to a date formatted as yyyy/mm/dd.
+type Parser interface {
+	Parse(text string) (string, error)
+}
+
+// Error reports text that could not be parsed as a date.
+type Error struct {
+	Text   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid date %q: %s", e.Text, e.Reason)
+}
+
+// DefaultLayouts are the layouts recognized by a Grammar without layouts of its own.
+// They cover Quicken (12/31'98, 12/31/1998), European banks (31.12.1998)
+// and GnuCash (1998-12-31).
+var DefaultLayouts = []string{"M/D'Y", "M/D/Y", "M-D-Y", "D.M.Y", "Y-M-D", "Y/M/D"}
+
+// DefaultPivot is the two-digit year pivot used by a Grammar without a pivot of its own.
+const DefaultPivot = 70
+
+// Default is the grammar used when no other parser is given.
+var Default = Grammar{}
+
+// Grammar is a Parser that accepts any date that matches one of its layouts.
+//
+// In a layout, M is a one or two digit month, D is a one or two digit day
+// and Y is a two or four digit year. The month, day and year may be padded
+// with a leading space, as Quicken does, and a padded one digit year is a
+// two digit year (' 4 is 2004). Every other character must match exactly.
+type Grammar struct {
+	// Layouts are the recognized layouts, tried in order.
+	// If empty, DefaultLayouts are used.
+	Layouts []string
+	// DayFirst reads every layout that has the month before the day
+	// and ends with the year as day-first (e.g., "M/D/Y" is read as "D/M/Y").
+	DayFirst bool
+	// Pivot is the first two-digit year that is in the 20th century.
+	// Two-digit years less than the pivot are in the 21st century.
+	// If zero, DefaultPivot is used. A pivot of 100 puts every
+	// two-digit year in the 21st century.
+	Pivot int
+}
+
+// Parse implements the Parser interface.
+func (g Grammar) Parse(text string) (string, error) {
+	layouts := g.Layouts
+	if len(layouts) == 0 {
+		layouts = DefaultLayouts
+	}
+	pivot := g.Pivot
+	if pivot == 0 {
+		pivot = DefaultPivot
+	}
+
+	value := strings.TrimSpace(text)
+	reason := "does not match any layout"
+	for _, layout := range layouts {
+		if g.DayFirst {
+			layout = dayFirst(layout)
+		}
+		yy, mm, dd, digits, ok := match(layout, value)
+		if !ok {
+			continue
+		}
+		if digits == 2 {
+			if yy < pivot {
+				yy += 2000
+			} else {
+				yy += 1900
+			}
+		}
+		if mm < 1 || mm > 12 {
+			reason = "month out of range"
+			continue
+		} else if dd < 1 || dd > daysIn(yy, mm) {
+			reason = "day out of range"
+			continue
+		}
+		return fmt.Sprintf("%04d/%02d/%02d", yy, mm, dd), nil
+	}
+
+	return "", &Error{Text: text, Reason: reason}
+}
+
+// dayFirst swaps the month and day of a month-first layout that ends with the year.
+func dayFirst(layout string) string {
+	m, d := strings.IndexByte(layout, 'M'), strings.IndexByte(layout, 'D')
+	if m == -1 || d == -1 || m > d || !strings.HasSuffix(layout, "Y") {
+		return layout
+	}
+	b := []byte(layout)
+	b[m], b[d] = 'D', 'M'
+	return string(b)
+}
+
+// match returns the year, month and day if the text matches the layout.
+// It also returns the number of digits in the year.
+func match(layout, text string) (yy, mm, dd, digits int, ok bool) {
+	pos := 0
+	for i := 0; i < len(layout); i++ {
+		switch ch := layout[i]; ch {
+		case 'M', 'D', 'Y':
+			padded := pos < len(text) && text[pos] == ' '
+			if padded {
+				pos++
+			}
+			start := pos
+			for pos < len(text) && '0' <= text[pos] && text[pos] <= '9' {
+				pos++
+			}
+			n, value := pos-start, 0
+			for _, digit := range text[start:pos] {
+				value = value*10 + int(digit-'0')
+			}
+			switch {
+			case ch == 'M' && (n == 1 || n == 2):
+				mm = value
+			case ch == 'D' && (n == 1 || n == 2):
+				dd = value
+			case ch == 'Y' && (n == 2 || n == 4):
+				yy, digits = value, n
+			case ch == 'Y' && n == 1 && padded:
+				// Quicken writes 2004 as ' 4
+				yy, digits = value, 2
+			default:
+				return 0, 0, 0, 0, false
+			}
+		default:
+			if pos >= len(text) || text[pos] != ch {
+				return 0, 0, 0, 0, false
+			}
+			pos++
+		}
+	}
+	return yy, mm, dd, digits, pos == len(text)
+}
+
+// daysIn returns the number of days in the month.
+func daysIn(yy, mm int) int {
+	switch mm {
+	case 2:
+		if yy%4 == 0 && (yy%100 != 0 || yy%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
+}
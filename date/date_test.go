@@ -0,0 +1,78 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package date
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		grammar Grammar
+		text    string
+		want    string // the reason if the text isn't a date
+	}{
+		{"quicken", Grammar{}, "12/31'98", "1998/12/31"},
+		{"quicken padded", Grammar{}, " 1/ 2' 4", "2004/01/02"},
+		{"four digit year", Grammar{}, "12/31/1998", "1998/12/31"},
+		{"dashes", Grammar{}, "12-31-1998", "1998/12/31"},
+		{"european", Grammar{}, "31.12.1998", "1998/12/31"},
+		{"gnucash", Grammar{}, "1998-12-31", "1998/12/31"},
+		{"year first", Grammar{}, "1998/12/31", "1998/12/31"},
+		{"surrounding space", Grammar{}, "  6/1'21 ", "2021/06/01"},
+		{"pivot minus one", Grammar{}, "1/1'69", "2069/01/01"},
+		{"pivot", Grammar{}, "1/1'70", "1970/01/01"},
+		{"pivot 2000", Grammar{Pivot: 100}, "1/1'98", "2098/01/01"},
+		{"pivot 1950", Grammar{Pivot: 50}, "1/1'50", "1950/01/01"},
+		{"day first", Grammar{DayFirst: true}, "31/12'98", "1998/12/31"},
+		{"day first four digit year", Grammar{DayFirst: true}, "3/4/2021", "2021/04/03"},
+		{"day first keeps year first", Grammar{DayFirst: true}, "2021/04/03", "2021/04/03"},
+		{"day first keeps dots", Grammar{DayFirst: true}, "3.4.2021", "2021/04/03"},
+		{"layouts", Grammar{Layouts: []string{"D M Y"}}, "3 4 2021", "2021/04/03"},
+		{"layouts replace the defaults", Grammar{Layouts: []string{"D M Y"}}, "4/3/2021", "does not match any layout"},
+		{"leap day", Grammar{}, "2/29/2000", "2000/02/29"},
+		{"not a leap day", Grammar{}, "2/29/1900", "day out of range"},
+		{"month first day out of range", Grammar{}, "4/31/2021", "day out of range"},
+		{"month out of range", Grammar{}, "13/1/2021", "month out of range"},
+		{"day first month out of range", Grammar{DayFirst: true}, "1/13/2021", "month out of range"},
+		{"three digit year", Grammar{}, "1/1/198", "does not match any layout"},
+		{"trailing text", Grammar{}, "1/1/1998x", "does not match any layout"},
+		{"empty", Grammar{}, "", "does not match any layout"},
+	} {
+		got, err := tc.grammar.Parse(tc.text)
+		var e *Error
+		if errors.As(err, &e) {
+			if e.Text != tc.text || e.Reason != tc.want {
+				t.Errorf("%s: Parse(%q): %v, want %s", tc.name, tc.text, err, tc.want)
+			}
+		} else if err != nil {
+			t.Errorf("%s: Parse(%q): unexpected error %v", tc.name, tc.text, err)
+		} else if got != tc.want {
+			t.Errorf("%s: Parse(%q) = %q, want %q", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
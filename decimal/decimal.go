@@ -0,0 +1,381 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package decimal implements the exact fixed-point numbers used for
+// amounts, prices and quantities.
+package decimal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxScale is the largest number of digits kept after the decimal point.
+const MaxScale = 9
+
+// Decimal is an exact decimal number.
+// The zero value is zero.
+//
+// The arithmetic never overflows. A result with too many digits keeps
+// fewer digits after the decimal point, rounded, and a result that is too
+// large even without a fraction saturates at the largest or smallest value.
+type Decimal struct {
+	value int64 // the number multiplied by 10^scale
+	scale int   // the number of digits after the decimal point
+}
+
+// Error reports text that could not be parsed as a number.
+type Error struct {
+	Text   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid amount %q: %s", e.Text, e.Reason)
+}
+
+// New returns value * 10^-scale.
+func New(value int64, scale int) Decimal {
+	return fit(big.NewInt(value), scale)
+}
+
+// Parse returns the number in the text. It accepts every spelling of an
+// amount that Quicken writes: an optional currency symbol, a leading or
+// trailing sign or surrounding parentheses for negative amounts, commas
+// grouping the thousands and a period before the fraction.
+// For example, "1,234.56", "-$12.00", "$-12.00", "(1,234.56)" and "12.50-".
+// Empty text is zero. Use a Locale to parse numbers written with other separators.
+func Parse(text string) (Decimal, error) {
+	return US.Parse(text)
+}
+
+// Max and Min are the values that results too large to represent saturate at.
+var (
+	Max = Decimal{value: math.MaxInt64}
+	Min = Decimal{value: math.MinInt64}
+)
+
+// MustParse is like Parse but panics if the text is not a number.
+// It is intended for constants in code, never for input.
+func MustParse(text string) Decimal {
+	d, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// parse returns the number in the text using the decimal and grouping separators.
+func parse(text string, point, group rune) (Decimal, error) {
+	s := strings.TrimSpace(text)
+	if s == "" {
+		return Decimal{}, nil
+	} else if strings.ContainsRune(s, '/') {
+		return parseFraction(text, s, point, group)
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, strings.TrimSpace(s[1:len(s)-1])
+	}
+
+	var value int64
+	var digits, scale int
+	var sign, seenPoint bool
+	for i, ch := range s {
+		switch {
+		case '0' <= ch && ch <= '9':
+			if digits >= 18 {
+				return Decimal{}, &Error{Text: text, Reason: "too many digits"}
+			}
+			value, digits = value*10+int64(ch-'0'), digits+1
+			if seenPoint {
+				scale++
+			}
+		case ch == point:
+			if seenPoint {
+				return Decimal{}, &Error{Text: text, Reason: "more than one decimal separator"}
+			}
+			seenPoint = true
+		case ch == group:
+			if seenPoint {
+				return Decimal{}, &Error{Text: text, Reason: "grouping separator after the decimal separator"}
+			}
+		case ch == '-' || ch == '+':
+			// a sign may lead or trail the digits, but not both
+			if sign || (digits != 0 && i != len(s)-1) {
+				return Decimal{}, &Error{Text: text, Reason: "misplaced sign"}
+			}
+			sign = true
+			if ch == '-' {
+				negative = !negative
+			}
+		case strings.ContainsRune(currencySymbols, ch) || ch == ' ' || ch == '\u00a0':
+			// currency symbols and spaces are ignored
+		default:
+			return Decimal{}, &Error{Text: text, Reason: fmt.Sprintf("unexpected character %q", ch)}
+		}
+	}
+	if digits == 0 {
+		return Decimal{}, &Error{Text: text, Reason: "no digits"}
+	}
+
+	if negative {
+		value = -value
+	}
+	return New(value, scale), nil
+}
+
+// parseFraction returns the number in a fraction such as "3/8" or "102 3/8",
+// which is how Quicken writes the prices of some securities. The whole
+// number is parsed with the separators and its sign applies to the fraction.
+// A fraction that can't be written exactly is rounded to MaxScale digits.
+func parseFraction(text, s string, point, group rune) (Decimal, error) {
+	fields := strings.Fields(s)
+	var whole Decimal
+	if len(fields) == 2 {
+		var err error
+		if whole, err = parse(fields[0], point, group); err != nil {
+			return Decimal{}, &Error{Text: text, Reason: "invalid whole number before the fraction"}
+		}
+		fields = fields[1:]
+	} else if len(fields) != 1 {
+		return Decimal{}, &Error{Text: text, Reason: "misplaced fraction"}
+	}
+	negative := whole.Sign() < 0 || strings.HasPrefix(strings.TrimSpace(s), "-")
+	parts := strings.Split(strings.TrimPrefix(fields[0], "-"), "/")
+	if len(parts) != 2 {
+		return Decimal{}, &Error{Text: text, Reason: "more than one fraction bar"}
+	}
+	num, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || num < 0 {
+		return Decimal{}, &Error{Text: text, Reason: "invalid numerator"}
+	}
+	den, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || den <= 0 {
+		return Decimal{}, &Error{Text: text, Reason: "invalid denominator"}
+	}
+
+	// use the fewest digits that write the fraction exactly
+	scale, ten := 0, big.NewInt(10)
+	n, d := big.NewInt(num), big.NewInt(den)
+	for scale < MaxScale && new(big.Int).Rem(n, d).Sign() != 0 {
+		n.Mul(n, ten)
+		scale++
+	}
+	fraction := fit(quo(n, d), scale)
+	if negative {
+		fraction = fraction.Neg()
+	}
+	return whole.Add(fraction), nil
+}
+
+// currencySymbols are the symbols ignored when parsing an amount.
+const currencySymbols = "$€£¥¢"
+
+// Abs returns the absolute value of d.
+func (d Decimal) Abs() Decimal {
+	if d.value < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// Add returns d + e.
+func (d Decimal) Add(e Decimal) Decimal {
+	a, b, scale := align(d, e)
+	return fit(a.Add(a, b), scale)
+}
+
+// Cmp returns -1 if d < e, 0 if d == e and +1 if d > e.
+func (d Decimal) Cmp(e Decimal) int {
+	a, b, _ := align(d, e)
+	return a.Cmp(b)
+}
+
+// Equal returns true if d and e are the same number, regardless of scale.
+func (d Decimal) Equal(e Decimal) bool {
+	return d.Cmp(e) == 0
+}
+
+// IsZero returns true if d is zero.
+func (d Decimal) IsZero() bool {
+	return d.value == 0
+}
+
+// Mul returns d * e, rounded to MaxScale digits.
+func (d Decimal) Mul(e Decimal) Decimal {
+	return fit(new(big.Int).Mul(big.NewInt(d.value), big.NewInt(e.value)), d.scale+e.scale)
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return fit(new(big.Int).Neg(big.NewInt(d.value)), d.scale)
+}
+
+// Round returns d rounded half away from zero to the number of digits after the decimal point.
+// If d has fewer digits, it is returned with trailing zeros added.
+func (d Decimal) Round(places int) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	if d.scale < places {
+		// fit keeps fewer places if the digits don't fit
+		return fit(new(big.Int).Mul(big.NewInt(d.value), pow10(places-d.scale)), places)
+	}
+	for d.scale > places {
+		q, r := d.value/10, d.value%10
+		if d.scale == places+1 {
+			if r >= 5 {
+				q++
+			} else if r <= -5 {
+				q--
+			}
+		}
+		d.value, d.scale = q, d.scale-1
+	}
+	return d
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Sign returns -1 if d is negative, 0 if d is zero and +1 if d is positive.
+func (d Decimal) Sign() int {
+	if d.value < 0 {
+		return -1
+	} else if d.value > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Sub returns d - e.
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d.Add(e.Neg())
+}
+
+// String returns the canonical text of d: an optional minus sign, the digits and,
+// if the scale is not zero, a period followed by exactly scale digits.
+// For example, "-1234.50".
+func (d Decimal) String() string {
+	digits := strconv.FormatInt(d.value, 10)
+	negative := d.value < 0
+	if negative {
+		digits = digits[1:]
+	}
+	if d.scale > 0 {
+		if len(digits) <= d.scale {
+			digits = strings.Repeat("0", d.scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-d.scale] + "." + digits[len(digits)-d.scale:]
+	}
+	if negative {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The number is written as a string so that it is never rounded by a float.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts either a string or a number.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+	text := string(b)
+	if len(b) != 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &text); err != nil {
+			return err
+		}
+	}
+	v, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// align returns the values of d and e with the same scale, and the scale.
+func align(d, e Decimal) (*big.Int, *big.Int, int) {
+	a, b := big.NewInt(d.value), big.NewInt(e.value)
+	if d.scale < e.scale {
+		a.Mul(a, pow10(e.scale-d.scale))
+		return a, b, e.scale
+	}
+	b.Mul(b, pow10(d.scale-e.scale))
+	return a, b, d.scale
+}
+
+// fit returns value * 10^-scale as a Decimal. Digits after the decimal
+// point are rounded away until there are at most MaxScale of them and the
+// value fits in an int64. If it still doesn't fit, it saturates.
+func fit(value *big.Int, scale int) Decimal {
+	if scale < 0 {
+		value, scale = new(big.Int).Mul(value, pow10(-scale)), 0
+	}
+	drop := 0
+	if scale > MaxScale {
+		drop = scale - MaxScale
+	}
+	v := quo(value, pow10(drop))
+	for !v.IsInt64() && drop < scale {
+		drop++
+		v = quo(value, pow10(drop))
+	}
+	if !v.IsInt64() {
+		if v.Sign() < 0 {
+			return Min
+		}
+		return Max
+	}
+	return Decimal{value: v.Int64(), scale: scale - drop}
+}
+
+// quo returns n / d rounded half away from zero.
+func quo(n, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Abs(r).Lsh(r, 1).Cmp(d) >= 0 {
+		q.Add(q, big.NewInt(int64(n.Sign())))
+	}
+	return q
+}
+
+// pow10 returns 10^n.
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
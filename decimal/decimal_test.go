@@ -0,0 +1,173 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package decimal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want string // "error" if the text isn't a number
+	}{
+		{"", "0"},
+		{"0.00", "0.00"},
+		{"1,234.56", "1234.56"},
+		{"-$12.00", "-12.00"},
+		{"$-12.00", "-12.00"},
+		{"(1,234.56)", "-1234.56"},
+		{"12.50-", "-12.50"},
+		{"+7", "7"},
+		{" 42 ", "42"},
+		{"€3.5", "3.5"},
+		{"0.1234567891", "0.123456789"},
+		{"102 3/8", "102.375"},
+		{"-102 3/8", "-102.375"},
+		{"3/8", "0.375"},
+		{"-1/64", "-0.015625"},
+		{"1/3", "0.333333333"},
+		{"2/3", "0.666666667"},
+		{"1,000 1/2", "1000.5"},
+		{"1/0", "error"},
+		{"1/2/3", "error"},
+		{"a/2", "error"},
+		{"1 2 3/4", "error"},
+		{"12.3.4", "error"},
+		{"1.2,3", "error"},
+		{"-12-", "error"},
+		{"1-2", "error"},
+		{"12x", "error"},
+		{"$", "error"},
+		{"1234567890123456789", "error"},
+	} {
+		got, err := Parse(tc.text)
+		switch {
+		case tc.want == "error" && err == nil:
+			t.Errorf("Parse(%q) = %s, want an error", tc.text, got)
+		case tc.want != "error" && err != nil:
+			t.Errorf("Parse(%q): %v", tc.text, err)
+		case tc.want != "error" && got.String() != tc.want:
+			t.Errorf("Parse(%q) = %s, want %s", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	for _, tc := range []struct {
+		text   string
+		places int
+		want   string
+	}{
+		{"1.005", 2, "1.01"},
+		{"-1.005", 2, "-1.01"},
+		{"1.004", 2, "1.00"},
+		{"0.445", 1, "0.4"},
+		{"2.5", 0, "3"},
+		{"-2.5", 0, "-3"},
+		{"12", 2, "12.00"},
+		{"12.345", -1, "12"},
+	} {
+		if got := MustParse(tc.text).Round(tc.places); got.String() != tc.want {
+			t.Errorf("%s.Round(%d) = %s, want %s", tc.text, tc.places, got, tc.want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want int
+	}{
+		{"-0.01", -1}, {"0.00", 0}, {"", 0}, {"(3)", -1}, {"3", 1},
+	} {
+		if got := MustParse(tc.text).Sign(); got != tc.want {
+			t.Errorf("%q.Sign() = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"add aligns the scale", MustParse("1.5").Add(MustParse("2.25")), "3.75"},
+		{"sub", MustParse("1").Sub(MustParse("2.50")), "-1.50"},
+		{"mul", MustParse("100").Mul(MustParse("12.50")), "1250.00"},
+		{"mul rounds to MaxScale", MustParse("0.123456789").Mul(MustParse("0.5")), "0.061728395"},
+		{"neg", MustParse("-3").Neg(), "3"},
+		{"abs", MustParse("-3.10").Abs(), "3.10"},
+		{"new with a negative scale", New(12, -2), "1200"},
+		// a large quantity times a price keeps fewer digits after the decimal point
+		{"mul drops fraction digits", MustParse("123456789012.345").Mul(MustParse("1000.125")), "123472221110971.5431"},
+		{"add drops fraction digits", New(950000000000000000, 0).Add(MustParse("0.5")), "950000000000000001"},
+	} {
+		if tc.got.String() != tc.want {
+			t.Errorf("%s = %s, want %s", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestOverflowSaturates(t *testing.T) {
+	big := MustParse("900000000000000000")
+	for _, tc := range []struct {
+		name string
+		got  Decimal
+		want Decimal
+	}{
+		{"add", big.Add(big).Add(big).Add(big).Add(big).Add(big).Add(big).Add(big).Add(big).Add(big).Add(big), Max},
+		{"sub", big.Neg().Sub(big).Sub(big).Sub(big).Sub(big).Sub(big).Sub(big).Sub(big).Sub(big).Sub(big).Sub(big), Min},
+		{"mul", big.Mul(big), Max},
+		{"mul negative", big.Neg().Mul(big), Min},
+		{"neg of min", Min.Neg(), Max},
+		{"new", New(900000000000000000, -2), Max},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %s, want %s", tc.name, tc.got, tc.want)
+		}
+	}
+	if got := MustParse("1").Add(Max); got != Max {
+		t.Errorf("1 + Max = %s, want Max", got)
+	}
+	if MustParse("1").Cmp(Max) != -1 || Min.Cmp(Max) != -1 || Max.Cmp(MustParse("0.000000001")) != 1 {
+		t.Errorf("Cmp doesn't order values at the extremes")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	buf, err := json.Marshal(MustParse("-1234.50"))
+	if err != nil || string(buf) != `"-1234.50"` {
+		t.Errorf("Marshal = %s, %v", buf, err)
+	}
+	for _, text := range []string{`"12.50"`, `12.50`} {
+		var d Decimal
+		if err := json.Unmarshal([]byte(text), &d); err != nil || d.String() != "12.50" {
+			t.Errorf("Unmarshal(%s) = %s, %v", text, d, err)
+		}
+	}
+}
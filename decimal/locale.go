@@ -0,0 +1,171 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package decimal
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Parser converts the text of a QIF number to a Decimal.
+type Parser interface {
+	Parse(text string) (Decimal, error)
+}
+
+// Locale is a Parser for numbers written with a particular
+// decimal separator and grouping separator.
+type Locale struct {
+	Decimal rune // separates the whole number from the fraction
+	Group   rune // separates groups of digits in the whole number
+}
+
+// The common locales.
+var (
+	US       = Locale{Decimal: '.', Group: ','}  // 1,234.56
+	European = Locale{Decimal: ',', Group: '.'}  // 1.234,56
+	Swiss    = Locale{Decimal: '.', Group: '\''} // 1'234.56
+)
+
+// LocaleByName returns the locale for a name.
+// It accepts "us", "eu" and "ch", and the aliases "en", "de" and "european".
+func LocaleByName(name string) (Locale, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "us", "en", "":
+		return US, nil
+	case "eu", "de", "european":
+		return European, nil
+	case "ch", "swiss":
+		return Swiss, nil
+	}
+	return Locale{}, fmt.Errorf("decimal: unknown locale %q", name)
+}
+
+// Parse implements the Parser interface.
+// Apart from the separators, it accepts the same spellings as the package Parse.
+func (l Locale) Parse(text string) (Decimal, error) {
+	return parse(text, l.Decimal, l.Group)
+}
+
+// Auto is a Parser that detects the locale of a file from the numbers in it.
+// The locale is set by the first number that can only be written in one
+// locale (such as "12,5" or "1.234,56"), and every number after it is
+// parsed with that locale. Before then, numbers without separators are
+// parsed as usual, but a number such as "1,234" or "1.234" is an error,
+// since guessing could make it off by a factor of a thousand. Call Prescan
+// with the text of the file to detect the locale before parsing.
+// Use a new Auto for each file.
+type Auto struct {
+	locale   Locale
+	detected bool
+}
+
+// Locale returns the detected locale.
+// It returns false if the locale has not been detected.
+func (a *Auto) Locale() (Locale, bool) {
+	return a.locale, a.detected
+}
+
+// Parse implements the Parser interface.
+func (a *Auto) Parse(text string) (Decimal, error) {
+	if !a.detected {
+		a.locale, a.detected = detect(text)
+	}
+	if !a.detected {
+		if strings.ContainsAny(text, ",.") {
+			return Decimal{}, &Error{Text: text, Reason: "the separators could be US or European, and the number locale isn't known yet"}
+		}
+		return US.Parse(text)
+	}
+	return a.locale.Parse(text)
+}
+
+// Prescan detects the locale from the numbers in the text of a QIF file.
+// It looks only at the fields that hold amounts, prices and quantities,
+// and stops at the first number that can only be written in one locale.
+func (a *Auto) Prescan(text []byte) {
+	for _, line := range bytes.Split(text, []byte{'\n'}) {
+		if a.detected {
+			return
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) < 2 || bytes.IndexByte([]byte("$BILOQTU"), line[0]) == -1 || !isNumber(line[1:]) {
+			continue
+		}
+		a.locale, a.detected = detect(string(line[1:]))
+	}
+}
+
+// isNumber returns true if the text has digits and only the characters of a number.
+func isNumber(text []byte) bool {
+	digits := false
+	for _, ch := range text {
+		if '0' <= ch && ch <= '9' {
+			digits = true
+		} else if bytes.IndexByte([]byte(" $'()+,-."), ch) == -1 {
+			return false
+		}
+	}
+	return digits
+}
+
+// detect returns the locale if the number can only be written in one locale.
+func detect(text string) (Locale, bool) {
+	commas, periods := strings.Count(text, ","), strings.Count(text, ".")
+	switch {
+	case commas != 0 && periods != 0:
+		// the last separator is the decimal separator
+		if strings.LastIndexByte(text, ',') > strings.LastIndexByte(text, '.') {
+			return European, true
+		}
+		return US, true
+	case commas > 1:
+		return US, true
+	case periods > 1:
+		return European, true
+	case commas == 1:
+		// a comma before exactly three digits may be a grouping separator
+		if fractionDigits(text, ',') != 3 {
+			return European, true
+		}
+	case periods == 1:
+		if fractionDigits(text, '.') != 3 {
+			return US, true
+		}
+	}
+	return Locale{}, false
+}
+
+// fractionDigits returns the number of digits following the separator.
+func fractionDigits(text string, sep byte) (n int) {
+	for _, ch := range text[strings.IndexByte(text, sep)+1:] {
+		if '0' <= ch && ch <= '9' {
+			n++
+		} else if ch != ' ' {
+			break
+		}
+	}
+	return n
+}
@@ -0,0 +1,133 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package decimal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLocaleParse(t *testing.T) {
+	for _, tc := range []struct {
+		locale Locale
+		text   string
+		want   string
+	}{
+		{US, "1,234.56", "1234.56"},
+		{US, "-1,234", "-1234"},
+		{European, "1.234,56", "1234.56"},
+		{European, "(12,5)", "-12.5"},
+		{Swiss, "1'234.56", "1234.56"},
+	} {
+		got, err := tc.locale.Parse(tc.text)
+		if err != nil {
+			t.Errorf("%+v.Parse(%q): %v", tc.locale, tc.text, err)
+		} else if got.String() != tc.want {
+			t.Errorf("%+v.Parse(%q) = %s, want %s", tc.locale, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestLocaleByName(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want Locale
+	}{
+		{"us", US}, {"", US}, {"EU", European}, {"de", European}, {"ch", Swiss},
+	} {
+		if got, err := LocaleByName(tc.name); err != nil || got != tc.want {
+			t.Errorf("LocaleByName(%q) = %+v, %v, want %+v", tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := LocaleByName("fr"); err == nil {
+		t.Errorf("LocaleByName(%q): want an error", "fr")
+	}
+}
+
+func TestAuto(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		texts  []string
+		want   []string // "error" if the number can't be parsed
+		locale *Locale  // the detected locale, nil if none
+	}{
+		{"no separators", []string{"12", "-3"}, []string{"12", "-3"}, nil},
+		{"us", []string{"12", "12.50", "1,234"}, []string{"12", "12.50", "1234"}, &US},
+		{"european", []string{"12,5", "1.234"}, []string{"12.5", "1234"}, &European},
+		{"european group and decimal", []string{"1.234,56", "1,5"}, []string{"1234.56", "1.5"}, &European},
+		{"ambiguous before detection", []string{"1.234", "12,50"}, []string{"error", "12.50"}, &European},
+		{"ambiguous comma", []string{"1,234"}, []string{"error"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var a Auto
+			for i, text := range tc.texts {
+				got, err := a.Parse(text)
+				var e *Error
+				switch {
+				case tc.want[i] == "error" && !errors.As(err, &e):
+					t.Errorf("Parse(%q) = %s, %v, want an *Error", text, got, err)
+				case tc.want[i] != "error" && (err != nil || got.String() != tc.want[i]):
+					t.Errorf("Parse(%q) = %s, %v, want %s", text, got, err, tc.want[i])
+				}
+			}
+			locale, ok := a.Locale()
+			if tc.locale == nil && ok {
+				t.Errorf("detected %+v, want none", locale)
+			} else if tc.locale != nil && (!ok || locale != *tc.locale) {
+				t.Errorf("detected %+v %v, want %+v", locale, ok, *tc.locale)
+			}
+		})
+	}
+}
+
+func TestAutoPrescan(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		text   string
+		locale *Locale
+	}{
+		{"european after an ambiguous amount", "!Type:Bank\nD1/2/21\nT-1.234\nPShop 1,5 kg\n^\nT-12,50\n^\n", &European},
+		{"us", "!Type:Bank\nT1,234\nU1,234.00\n^\n", &US},
+		{"text fields are ignored", "!Type:Bank\nPSmith, J.\nM1.5,2\nLAuto:1,5\n^\n", nil},
+		{"statement balance", "!Account\nNChecking\n$1.234,50\n^\n", &European},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var a Auto
+			a.Prescan([]byte(tc.text))
+			locale, ok := a.Locale()
+			if tc.locale == nil && ok {
+				t.Errorf("detected %+v, want none", locale)
+			} else if tc.locale != nil && (!ok || locale != *tc.locale) {
+				t.Errorf("detected %+v %v, want %+v", locale, ok, *tc.locale)
+			}
+		})
+	}
+	// after a prescan, the ambiguous amounts before the first decisive one are parsed with the locale
+	var a Auto
+	a.Prescan([]byte("T-1.234\n^\nT-12,50\n^\n"))
+	if got, err := a.Parse("-1.234"); err != nil || got.String() != "-1234" {
+		t.Errorf("Parse(%q) = %s, %v, want -1234", "-1.234", got, err)
+	}
+}
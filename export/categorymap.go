@@ -0,0 +1,56 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+)
+
+// UnmappedName is a category or class that the category map doesn't rename.
+type UnmappedName struct {
+	Name string `json:"name"`
+	Uses int    `json:"uses"`
+}
+
+// EncodeCategoryReport writes the document of the categories and classes that weren't mapped.
+func EncodeCategoryReport(w io.Writer, report *transformer.CategoryReport) error {
+	data := struct {
+		Categories []UnmappedName `json:"unmapped_categories"`
+		Classes    []UnmappedName `json:"unmapped_classes"`
+	}{
+		Categories: []UnmappedName{},
+		Classes:    []UnmappedName{},
+	}
+	if report != nil {
+		for _, u := range report.Categories {
+			data.Categories = append(data.Categories, UnmappedName{Name: u.Name, Uses: u.Uses})
+		}
+		for _, u := range report.Classes {
+			data.Classes = append(data.Classes, UnmappedName{Name: u.Name, Uses: u.Uses})
+		}
+	}
+	return encode(w, data)
+}
@@ -0,0 +1,191 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+	"strconv"
+)
+
+// Layout is the shape of the rows written by WriteCSV.
+type Layout string
+
+const (
+	// PerTransaction writes one row for each transaction.
+	// The split columns are filled in only if the transaction has a single split.
+	PerTransaction Layout = "transaction"
+	// PerSplit writes one row for each split, repeating the transaction columns.
+	PerSplit Layout = "split"
+)
+
+// The columns that WriteCSV can write.
+// The names match the fields in the transactions document.
+var CSVColumns = []string{
+	"line",           // line of the transaction in the QIF file
+	"type",           // type of the transaction
+	"date",           // date of the transaction
+	"account",        // account the transaction is in
+	"payee",          // payee of the transaction
+	"original_payee", // payee before it was normalized by the payee rules
+	"ref_no",         // check or reference number
+	"cleared_status", // cleared status of the transaction
+	"memo",           // memo of the transaction
+	"amount",         // amount of the split, or the total of the splits for PerTransaction
+	"category",       // category of the split
+	"class",          // class of the split
+	"to_account",     // account the split transfers to
+	"split_memo",     // memo of the split
+	"split_line",     // line of the split in the QIF file
+	"splits",         // number of splits in the transaction
+	"transfer_id",    // ID of the transfer the split is a half of
+}
+
+// DefaultCSVColumns are the columns written if none are given.
+var DefaultCSVColumns = []string{"date", "account", "payee", "ref_no", "cleared_status", "amount", "category", "class", "to_account", "memo", "split_memo"}
+
+// CSVOptions controls the output of WriteCSV.
+type CSVOptions struct {
+	Layout    Layout   // defaults to PerSplit
+	Columns   []string // defaults to DefaultCSVColumns
+	Delimiter rune     // defaults to a comma
+}
+
+// WriteCSV writes the transactions as CSV with a header row.
+// Fields are quoted as needed, so payees and memos may contain
+// delimiters, quotes and line breaks.
+func WriteCSV(w io.Writer, transactions []*transformer.Transaction, opts CSVOptions) error {
+	if opts.Layout == "" {
+		opts.Layout = PerSplit
+	} else if opts.Layout != PerTransaction && opts.Layout != PerSplit {
+		return fmt.Errorf("csv: unsupported layout %q", opts.Layout)
+	}
+	if len(opts.Columns) == 0 {
+		opts.Columns = DefaultCSVColumns
+	}
+	for _, column := range opts.Columns {
+		if !isCSVColumn(column) {
+			return fmt.Errorf("csv: unsupported column %q", column)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		cw.Comma = opts.Delimiter
+	}
+	if err := cw.Write(opts.Columns); err != nil {
+		return err
+	}
+	for _, t := range transactions {
+		if opts.Layout == PerTransaction {
+			var split *transformer.Split
+			if len(t.Split) == 1 {
+				split = t.Split[0]
+			}
+			if err := cw.Write(csvRow(opts.Columns, t, split, total(t))); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, split := range t.Split {
+			if err := cw.Write(csvRow(opts.Columns, t, split, split.Amount)); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvRow returns the values of the columns for the transaction and split.
+// The split is nil if the split columns should be empty.
+func csvRow(columns []string, t *transformer.Transaction, split *transformer.Split, amount decimal.Decimal) []string {
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "line":
+			row[i] = strconv.Itoa(t.Line)
+		case "type":
+			row[i] = t.Type
+		case "date":
+			row[i] = t.Date
+		case "account":
+			row[i] = t.Account
+		case "payee":
+			row[i] = t.Payee
+		case "original_payee":
+			row[i] = t.OriginalPayee
+		case "ref_no":
+			row[i] = t.RefNo
+		case "cleared_status":
+			row[i] = t.ClearedStatus
+		case "memo":
+			row[i] = t.Memo
+		case "amount":
+			row[i] = amount.String()
+		case "splits":
+			row[i] = strconv.Itoa(len(t.Split))
+		}
+		if split == nil {
+			continue
+		}
+		switch column {
+		case "category":
+			row[i] = split.Category
+		case "class":
+			row[i] = split.Class
+		case "to_account":
+			row[i] = split.Account
+		case "split_memo":
+			row[i] = split.Memo
+		case "split_line":
+			row[i] = strconv.Itoa(split.Line)
+		case "transfer_id":
+			row[i] = split.TransferID
+		}
+	}
+	return row
+}
+
+func isCSVColumn(name string) bool {
+	for _, column := range CSVColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// total returns the sum of the splits of the transaction.
+func total(t *transformer.Transaction) decimal.Decimal {
+	var sum decimal.Decimal
+	for _, split := range t.Split {
+		sum = sum.Add(split.Amount)
+	}
+	return sum
+}
@@ -0,0 +1,98 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/transformer"
+	"reflect"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	transactions := []*transformer.Transaction{
+		{Date: "2021/01/13", Account: "Checking", Payee: `Smith, "Bob"`, Memo: "line one\nline two", Amount: decimal.MustParse("-75.00"), Split: []*transformer.Split{
+			{Amount: decimal.MustParse("-25.00"), Category: "Auto:Fuel", Memo: "tab\there"},
+			{Amount: decimal.MustParse("-50.00"), Account: "Visa", Memo: "semi;colon"},
+		}},
+		{Date: "2021/01/14", Account: "Checking", Payee: "Grocer", Amount: decimal.MustParse("-5.00"), Split: []*transformer.Split{
+			{Amount: decimal.MustParse("-5.00"), Category: "Food"},
+		}},
+	}
+	columns := []string{"date", "payee", "memo", "amount", "category", "to_account", "split_memo"}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, transactions, CSVOptions{Columns: columns}); err != nil {
+		t.Fatal(err)
+	}
+	want := "date,payee,memo,amount,category,to_account,split_memo\n" +
+		"2021/01/13,\"Smith, \"\"Bob\"\"\",\"line one\nline two\",-25.00,Auto:Fuel,,tab\there\n" +
+		"2021/01/13,\"Smith, \"\"Bob\"\"\",\"line one\nline two\",-50.00,,Visa,semi;colon\n" +
+		"2021/01/14,Grocer,,-5.00,Food,,\n"
+	if buf.String() != want {
+		t.Errorf("per split: got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// the quoting must survive another delimiter, read back by a CSV reader
+	for _, tc := range []struct {
+		name string
+		opts CSVOptions
+		want [][]string
+	}{
+		{"tabs per split", CSVOptions{Columns: columns, Delimiter: '\t'}, [][]string{
+			columns,
+			{"2021/01/13", `Smith, "Bob"`, "line one\nline two", "-25.00", "Auto:Fuel", "", "tab\there"},
+			{"2021/01/13", `Smith, "Bob"`, "line one\nline two", "-50.00", "", "Visa", "semi;colon"},
+			{"2021/01/14", "Grocer", "", "-5.00", "Food", "", ""},
+		}},
+		{"semicolons per transaction", CSVOptions{Columns: columns, Delimiter: ';', Layout: PerTransaction}, [][]string{
+			columns,
+			{"2021/01/13", `Smith, "Bob"`, "line one\nline two", "-75.00", "", "", ""},
+			{"2021/01/14", "Grocer", "", "-5.00", "Food", "", ""},
+		}},
+	} {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, transactions, tc.opts); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		cr := csv.NewReader(&buf)
+		cr.Comma = tc.opts.Delimiter
+		got, err := cr.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: read back: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	for _, opts := range []CSVOptions{{Columns: []string{"date", "nope"}}, {Layout: "row"}} {
+		if err := WriteCSV(&bytes.Buffer{}, transactions, opts); err == nil {
+			t.Errorf("WriteCSV(%+v): got no error", opts)
+		}
+	}
+}
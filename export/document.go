@@ -0,0 +1,241 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/category"
+	"io"
+	"time"
+)
+
+// DocumentVersion is the version of the Document schema.
+// It changes when a field is removed or its meaning changes.
+const DocumentVersion = 1
+
+// Document is everything read from a QIF file as a single JSON document.
+// Records refer to each other by ID. An ID is derived from the name of the
+// record, or from its content if it has no name, so the same input always
+// produces the same IDs. A reference may name an account or category
+// that isn't in the document if the QIF file didn't list it.
+type Document struct {
+	Version      int                 `json:"version"`
+	Metadata     Metadata            `json:"metadata"`
+	Accounts     []Account           `json:"accounts"`
+	Categories   []Category          `json:"categories"`
+	Classes      []Class             `json:"classes"`
+	Securities   []Security          `json:"securities"`
+	Tags         []Tag               `json:"tags"`
+	Transactions []Transaction       `json:"transactions"`
+	Memorized    []Memorized         `json:"memorized"`
+	Prices       []Price             `json:"prices"`
+	Diagnostics  []reader.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Metadata describes where the document came from.
+type Metadata struct {
+	Source      string    `json:"source"`       // name of the QIF file
+	SHA256      string    `json:"sha256"`       // hash of the bytes of the QIF file
+	ParsedAt    time.Time `json:"parsed_at"`    // when the file was read
+	Tool        string    `json:"tool"`         // name of the program that wrote the document
+	ToolVersion string    `json:"tool_version"` // version of that program
+}
+
+// The IDs of the records in a Document.
+func AccountID(name string) string  { return id("account", name) }
+func CategoryID(name string) string { return id("category", name) }
+func ClassID(name string) string    { return id("class", name) }
+func SecurityID(name string) string { return id("security", name) }
+func TagID(name string) string      { return id("tag", name) }
+
+// NewDocument returns the document for the records read from a QIF file.
+func NewDocument(r *reader.Reader, metadata Metadata) (*Document, error) {
+	doc := Document{
+		Version:      DocumentVersion,
+		Metadata:     metadata,
+		Accounts:     []Account{},
+		Classes:      Classes(r.Classes),
+		Securities:   Securities(r.Securities),
+		Tags:         Tags(r.Tags),
+		Transactions: Transactions(r),
+		Prices:       Prices(r.Prices),
+		Diagnostics:  r.Diagnostics,
+	}
+
+	accounts, err := Accounts(r.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		account.ID = AccountID(account.Name)
+		doc.Accounts = append(doc.Accounts, account)
+	}
+
+	// the categories include the parents that weren't in the category list
+	doc.Categories = categoryList(categoryTree(r.Categories))
+
+	for i := range doc.Classes {
+		doc.Classes[i].ID = ClassID(doc.Classes[i].Name)
+	}
+
+	// prices refer to a security by ticker, investments by name
+	tickers := make(map[string]string)
+	for i := range doc.Securities {
+		doc.Securities[i].ID = SecurityID(doc.Securities[i].Name)
+		if doc.Securities[i].Ticker != "" {
+			tickers[doc.Securities[i].Ticker] = doc.Securities[i].Name
+		}
+	}
+	securityByTicker := func(ticker string) string {
+		if name, ok := tickers[ticker]; ok {
+			return SecurityID(name)
+		}
+		return SecurityID(ticker)
+	}
+
+	for i := range doc.Tags {
+		doc.Tags[i].ID = TagID(doc.Tags[i].Name)
+	}
+
+	seen := make(map[string]int)
+	for i := range doc.Transactions {
+		xact := &doc.Transactions[i]
+		content := *xact
+		content.Line, content.Split = 0, withoutLines(xact.Split)
+		xact.ID = contentID("transaction", content, seen)
+		xact.AccountID = AccountID(xact.Account)
+		xact.SecurityID = SecurityID(xact.Security)
+		xact.TransferAccountID = AccountID(xact.TransferAccount)
+		linkSplits(xact.Split)
+	}
+
+	if doc.Memorized, err = MemorizedTransactions(r.Memorized); err != nil {
+		return nil, err
+	}
+	for i := range doc.Memorized {
+		m := &doc.Memorized[i]
+		content := *m
+		content.Line, content.Split = 0, withoutLines(m.Split)
+		m.ID = contentID("memorized", content, seen)
+		m.ToAccountID = AccountID(m.ToAccount)
+		m.CategoryID = CategoryID(m.Category)
+		m.ClassID = ClassID(m.Class)
+		linkSplits(m.Split)
+	}
+
+	for i := range doc.Prices {
+		p := &doc.Prices[i]
+		content := *p
+		content.Line = 0
+		p.ID = contentID("price", content, seen)
+		p.SecurityID = securityByTicker(p.Ticker)
+	}
+
+	return &doc, nil
+}
+
+// categoryTree returns the hierarchy of the categories in the section,
+// which may be nil.
+func categoryTree(section *category.Section) *category.Tree {
+	if section == nil {
+		return category.NewTree(nil)
+	}
+	return section.Tree()
+}
+
+// categoryList returns the categories in the tree, parents before children.
+func categoryList(tree *category.Tree) []Category {
+	categories := []Category{}
+	tree.Walk(func(node *category.Node) {
+		c := Category{
+			ID:          CategoryID(node.Path),
+			Name:        node.Path,
+			Income:      node.IsIncome,
+			Synthesized: node.Synthesized(),
+		}
+		if node.Parent != nil {
+			c.ParentID = CategoryID(node.Parent.Path)
+		}
+		if node.Record != nil {
+			c.Description = node.Record.Description
+			c.TaxRelated = node.Record.IsTaxRelated
+			c.TaxSchedule = node.Record.TaxSchedule
+		}
+		categories = append(categories, c)
+	})
+	return categories
+}
+
+// EncodeDocument writes the document.
+func EncodeDocument(w io.Writer, doc *Document) error {
+	return encode(w, doc)
+}
+
+// linkSplits sets the references from the splits.
+func linkSplits(splits []Split) {
+	for i := range splits {
+		splits[i].AccountID = AccountID(splits[i].Account)
+		splits[i].CategoryID = CategoryID(splits[i].Category)
+		splits[i].ClassID = ClassID(splits[i].Class)
+	}
+}
+
+// withoutLines returns a copy of the splits without line numbers.
+func withoutLines(splits []Split) []Split {
+	var content []Split
+	for _, split := range splits {
+		split.Line = 0
+		content = append(content, split)
+	}
+	return content
+}
+
+// id returns the ID for a named record, or an empty string if there is no name.
+func id(kind, name string) string {
+	if name == "" {
+		return ""
+	}
+	return kind + ":" + name
+}
+
+// contentID returns an ID derived from a hash of the record.
+// The caller clears the line numbers so that the ID doesn't change
+// when records are added before it. Identical records are numbered
+// in the order they are seen.
+func contentID(kind string, record interface{}, seen map[string]int) string {
+	// the records are plain structs, so marshalling can't fail
+	buf, _ := json.Marshal(record)
+	sum := sha256.Sum256(buf)
+	key := kind + ":" + hex.EncodeToString(sum[:8])
+	seen[key]++
+	if n := seen[key]; n > 1 {
+		return fmt.Sprintf("%s-%d", key, n)
+	}
+	return key
+}
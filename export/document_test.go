@@ -0,0 +1,126 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"github.com/mdhender/qif2json/reader"
+	"strings"
+	"testing"
+)
+
+// ids has a category whose parent isn't listed, a security with a ticker,
+// a price for it and one for a ticker without a security, two identical
+// transactions, a split transfer and a memorized transaction.
+const ids = "!Type:Cat\nNDining\nE\n^\nNAuto:Fuel\nE\n^\n" +
+	"!Type:Class\nNBusiness\n^\nNHome\n^\n" +
+	"!Type:Memorized\nKP\nPGas Co\nT-45.00\nLAuto:Fuel/Home\n^\n" +
+	"!Type:Security\nNACME Corp\nSACME\n^\n" +
+	"!Type:Prices\n\"ACME\",12.50,\"1/13/21\"\n^\n\"XYZ\",3.00,\"1/13/21\"\n^\n" +
+	"!Type:Bank\nD1/1/2021\nT-5.00\nPCafe\nLDining/Business\n^\nD1/1/2021\nT-5.00\nPCafe\nLDining/Business\n^\n" +
+	"D1/2/2021\nT-75.00\nSAuto:Fuel/Home\n$-25.00\nS[Visa]\n$-50.00\n^\n" +
+	"!Type:Invst\nD1/3/2021\nNBuy\nYACME Corp\nQ10\nT125.00\n^\n"
+
+func TestNewDocumentIDs(t *testing.T) {
+	newDocument := func(input string) *Document {
+		r, err := reader.ReadFrom(strings.NewReader(input), reader.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := NewDocument(r, Metadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	doc := newDocument(ids)
+
+	// prices refer to the security with the ticker, or to the ticker if there isn't one
+	if len(doc.Prices) != 2 {
+		t.Fatalf("got %d prices, want 2", len(doc.Prices))
+	} else if got, want := doc.Prices[0].SecurityID, SecurityID("ACME Corp"); got != want {
+		t.Errorf("price 0: got security %q, want %q", got, want)
+	} else if got, want := doc.Prices[1].SecurityID, SecurityID("XYZ"); got != want {
+		t.Errorf("price 1: got security %q, want %q", got, want)
+	}
+
+	if len(doc.Memorized) != 1 {
+		t.Fatalf("got %d memorized transactions, want 1", len(doc.Memorized))
+	} else if m := doc.Memorized[0]; !strings.HasPrefix(m.ID, "memorized:") || m.CategoryID != CategoryID("Auto:Fuel") || m.ClassID != ClassID("Home") {
+		t.Errorf("memorized: got ID %q category %q class %q", m.ID, m.CategoryID, m.ClassID)
+	}
+
+	if len(doc.Transactions) != 4 {
+		t.Fatalf("got %d transactions, want 4", len(doc.Transactions))
+	}
+	first, second, split, buy := doc.Transactions[0], doc.Transactions[1], doc.Transactions[2], doc.Transactions[3]
+	if first.ID == "" || second.ID != first.ID+"-2" {
+		t.Errorf("identical transactions: got IDs %q and %q, want the second numbered", first.ID, second.ID)
+	}
+	if s := first.Split[0]; s.CategoryID != CategoryID("Dining") || s.ClassID != ClassID("Business") || s.AccountID != "" {
+		t.Errorf("split: got category %q class %q account %q", s.CategoryID, s.ClassID, s.AccountID)
+	}
+	if s := split.Split[0]; s.CategoryID != CategoryID("Auto:Fuel") || s.ClassID != ClassID("Home") {
+		t.Errorf("split 0: got category %q class %q", s.CategoryID, s.ClassID)
+	}
+	if s := split.Split[1]; s.AccountID != AccountID("Visa") || s.CategoryID != "" {
+		t.Errorf("split 1: got account %q category %q", s.AccountID, s.CategoryID)
+	}
+	if buy.SecurityID != SecurityID("ACME Corp") {
+		t.Errorf("investment: got security %q", buy.SecurityID)
+	}
+
+	// every reference is to a record in the document, including the synthesized parent
+	known := make(map[string]bool)
+	for _, c := range doc.Categories {
+		known[c.ID] = true
+	}
+	for _, c := range doc.Classes {
+		known[c.ID] = true
+	}
+	for _, s := range doc.Securities {
+		known[s.ID] = true
+	}
+	for _, id := range []string{CategoryID("Dining"), CategoryID("Auto"), CategoryID("Auto:Fuel"), ClassID("Business"), ClassID("Home"), SecurityID("ACME Corp")} {
+		if !known[id] {
+			t.Errorf("%q: not in the document", id)
+		}
+	}
+
+	// the IDs don't depend on the line numbers
+	shifted := newDocument("\n\n\n" + ids)
+	for i := range doc.Transactions {
+		if doc.Transactions[i].ID != shifted.Transactions[i].ID {
+			t.Errorf("transaction %d: ID changed from %q to %q when the lines moved", i, doc.Transactions[i].ID, shifted.Transactions[i].ID)
+		}
+	}
+	if doc.Memorized[0].ID != shifted.Memorized[0].ID {
+		t.Errorf("memorized: ID changed from %q to %q when the lines moved", doc.Memorized[0].ID, shifted.Memorized[0].ID)
+	}
+	for i := range doc.Prices {
+		if doc.Prices[i].ID != shifted.Prices[i].ID {
+			t.Errorf("price %d: ID changed from %q to %q when the lines moved", i, doc.Prices[i].ID, shifted.Prices[i].ID)
+		}
+	}
+}
@@ -0,0 +1,589 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package export converts the records read from a QIF file
+// into the JSON documents written by qif2json.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/account"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/class"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/reader/tag"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+	"sort"
+)
+
+type Account struct {
+	ID                   string           `json:"id,omitempty"`
+	Type                 string           `json:"type"`
+	Name                 string           `json:"name"`
+	CreditLimit          *decimal.Decimal `json:"credit_limit,omitempty"`
+	Description          string           `json:"descr,omitempty"`
+	StatementBalance     *decimal.Decimal `json:"balance,omitempty"`
+	StatementBalanceDate string           `json:"statement_date,omitempty"`
+}
+
+type Category struct {
+	ID          string `json:"id,omitempty"`
+	ParentID    string `json:"parent_id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"descr,omitempty"`
+	Income      bool   `json:"income,omitempty"`
+	TaxRelated  bool   `json:"tax_related,omitempty"`
+	TaxSchedule string `json:"tax_schedule,omitempty"`
+	Synthesized bool   `json:"synthesized,omitempty"`
+}
+
+// CategoryNode is a category with its subcategories.
+type CategoryNode struct {
+	Name        string          `json:"name"`
+	Path        string          `json:"path"`
+	Description string          `json:"descr,omitempty"`
+	Income      bool            `json:"income,omitempty"`
+	TaxRelated  bool            `json:"tax_related,omitempty"`
+	TaxSchedule string          `json:"tax_schedule,omitempty"`
+	Synthesized bool            `json:"synthesized,omitempty"`
+	Children    []*CategoryNode `json:"children,omitempty"`
+}
+
+type Class struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"descr,omitempty"`
+}
+
+type Security struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Ticker      string `json:"ticker,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"descr,omitempty"`
+	Risk        string `json:"risk,omitempty"`
+}
+
+type Tag struct {
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"descr,omitempty"`
+}
+
+// Memorized is a memorized transaction.
+type Memorized struct {
+	ID            string           `json:"id,omitempty"`
+	Line          int              `json:"line,omitempty"`
+	Type          string           `json:"type"`
+	Payee         string           `json:"payee,omitempty"`
+	OriginalPayee string           `json:"original_payee,omitempty"`
+	Amount        *decimal.Decimal `json:"amount,omitempty"`
+	ToAccount     string           `json:"to_account,omitempty"`
+	ToAccountID   string           `json:"to_account_id,omitempty"`
+	Category      string           `json:"category,omitempty"`
+	CategoryID    string           `json:"category_id,omitempty"`
+	Class         string           `json:"class,omitempty"`
+	ClassID       string           `json:"class_id,omitempty"`
+	ClearedStatus string           `json:"cleared_status,omitempty"`
+	Memo          string           `json:"memo,omitempty"`
+	Address       []string         `json:"address,omitempty"`
+	Amortization  []string         `json:"amortization,omitempty"` // the fields 1 to 7, empty if not set
+	Split         []Split          `json:"lines,omitempty"`
+}
+
+// Price is the price of a security on a date.
+type Price struct {
+	ID         string          `json:"id,omitempty"`
+	Line       int             `json:"line,omitempty"`
+	Ticker     string          `json:"ticker"`
+	SecurityID string          `json:"security_id,omitempty"`
+	Date       string          `json:"date"`
+	Price      decimal.Decimal `json:"price"`
+}
+
+type Split struct {
+	Line       int             `json:"line,omitempty"`
+	Account    string          `json:"account,omitempty"`
+	AccountID  string          `json:"account_id,omitempty"`
+	Amount     decimal.Decimal `json:"amount"`
+	Category   string          `json:"category,omitempty"`
+	CategoryID string          `json:"category_id,omitempty"`
+	Class      string          `json:"class,omitempty"`
+	ClassID    string          `json:"class_id,omitempty"`
+	Memo       string          `json:"memo,omitempty"`
+}
+
+type Transaction struct {
+	ID                string           `json:"id,omitempty"`
+	Line              int              `json:"line,omitempty"`
+	Type              string           `json:"type,omitempty"` // "investment", or the type of the account section
+	Date              string           `json:"date,omitempty"`
+	Account           string           `json:"account,omitempty"`
+	AccountID         string           `json:"account_id,omitempty"`
+	ToAccount         string           `json:"to_account,omitempty"`
+	Amount            *decimal.Decimal `json:"amount,omitempty"` // the T amount, nil for investments
+	Category          string           `json:"category,omitempty"`
+	Class             string           `json:"class,omitempty"`
+	ClearedStatus     string           `json:"cleared_status,omitempty"`
+	Memo              string           `json:"memo,omitempty"`
+	Payee             string           `json:"payee,omitempty"`
+	OriginalPayee     string           `json:"original_payee,omitempty"`
+	RefNo             string           `json:"ref_no,omitempty"`
+	Split             []Split          `json:"lines,omitempty"`
+	Action            string           `json:"action,omitempty"`
+	Security          string           `json:"security,omitempty"`
+	SecurityID        string           `json:"security_id,omitempty"`
+	Shares            *decimal.Decimal `json:"shares,omitempty"`
+	Price             *decimal.Decimal `json:"price,omitempty"`
+	Commission        *decimal.Decimal `json:"commission,omitempty"`
+	Total             *decimal.Decimal `json:"total,omitempty"`
+	TransferAccount   string           `json:"transfer_account,omitempty"`
+	TransferAccountID string           `json:"transfer_account_id,omitempty"`
+	TransferAmount    *decimal.Decimal `json:"transfer_amount,omitempty"`
+}
+
+// accountTypes maps the Quicken account types to the types in the JSON.
+var accountTypes = map[string]string{
+	"Bank":          "bank",
+	"CCard":         "creditCard",
+	"Cash":          "cash",
+	"Oth A":         "asset",
+	"Oth L":         "liability",
+	"Invst":         "investment",
+	"Mutual":        "mutualFund",
+	"Port":          "brokerage",
+	"401(k)/403(b)": "retirement",
+}
+
+// memorizedTypes maps the K field of a memorized transaction to the type in the JSON.
+var memorizedTypes = map[string]string{
+	"C": "check",
+	"D": "deposit",
+	"P": "payment",
+	"I": "investment",
+	"E": "electronicPayee",
+}
+
+// Accounts returns the accounts in the section.
+// It returns an error if an account has a type that isn't supported.
+func Accounts(section *account.Section) ([]Account, error) {
+	var accounts []Account
+	if section == nil {
+		return accounts, nil
+	}
+	for _, record := range section.Records {
+		account, err := accountOf(record)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func accountOf(record *account.Record) (Account, error) {
+	typ, ok := accountTypes[record.Type]
+	if !ok {
+		return Account{}, fmt.Errorf("%d: account %q: unsupported account type %q", record.Line, record.Name, record.Type)
+	}
+	return Account{
+		Type:                 typ,
+		Name:                 record.Name,
+		CreditLimit:          nonZero(record.CreditLimit),
+		Description:          record.Description,
+		StatementBalance:     nonZero(record.StatementBalance),
+		StatementBalanceDate: record.StatementBalanceDate,
+	}, nil
+}
+
+// Categories returns the categories in the section, in the order they were read.
+func Categories(section *category.Section) []Category {
+	var categories []Category
+	if section == nil {
+		return categories
+	}
+	for _, record := range section.Records {
+		categories = append(categories, categoryOf(record))
+	}
+	return categories
+}
+
+func categoryOf(record *category.Record) Category {
+	return Category{
+		Name:        record.Name,
+		Description: record.Description,
+		Income:      record.IsIncome,
+		TaxRelated:  record.IsTaxRelated,
+		TaxSchedule: record.TaxSchedule,
+	}
+}
+
+// CategoryTree returns the top level categories in the section with their subcategories.
+func CategoryTree(section *category.Section) []*CategoryNode {
+	var roots []*CategoryNode
+	if section == nil {
+		return roots
+	}
+	var convert func(node *category.Node) *CategoryNode
+	convert = func(node *category.Node) *CategoryNode {
+		c := CategoryNode{Name: node.Name, Path: node.Path, Income: node.IsIncome, Synthesized: node.Synthesized()}
+		if node.Record != nil {
+			c.Description = node.Record.Description
+			c.TaxRelated = node.Record.IsTaxRelated
+			c.TaxSchedule = node.Record.TaxSchedule
+		}
+		for _, child := range node.Children {
+			c.Children = append(c.Children, convert(child))
+		}
+		return &c
+	}
+	for _, root := range section.Tree().Roots {
+		roots = append(roots, convert(root))
+	}
+	return roots
+}
+
+// Classes returns the classes in the section.
+func Classes(section *class.Section) []Class {
+	var classes []Class
+	if section == nil {
+		return classes
+	}
+	for _, record := range section.Records {
+		classes = append(classes, classOf(record))
+	}
+	return classes
+}
+
+func classOf(record *class.Record) Class {
+	return Class{Name: record.Name, Description: record.Description}
+}
+
+// Securities returns the securities in the section.
+func Securities(section *security.Section) []Security {
+	var securities []Security
+	if section == nil {
+		return securities
+	}
+	for _, record := range section.Records {
+		securities = append(securities, securityOf(record))
+	}
+	return securities
+}
+
+func securityOf(record *security.Record) Security {
+	return Security{
+		Name:        record.Name,
+		Ticker:      record.Ticker,
+		Type:        record.Type,
+		Description: record.Description,
+		Risk:        record.Risk,
+	}
+}
+
+// Tags returns the tags in the section.
+func Tags(section *tag.Section) []Tag {
+	var tags []Tag
+	if section == nil {
+		return tags
+	}
+	for _, record := range section.Records {
+		tags = append(tags, tagOf(record))
+	}
+	return tags
+}
+
+func tagOf(record *tag.Record) Tag {
+	return Tag{Name: record.Name, Description: record.Description}
+}
+
+// MemorizedTransactions returns the memorized transactions.
+// It returns an error if a transaction has a type that isn't supported.
+func MemorizedTransactions(records []*transaction.Record) ([]Memorized, error) {
+	var memorized []Memorized
+	for _, record := range records {
+		m, err := memorizedOf(record)
+		if err != nil {
+			return nil, err
+		}
+		memorized = append(memorized, m)
+	}
+	return memorized, nil
+}
+
+func memorizedOf(record *transaction.Record) (Memorized, error) {
+	typ, ok := memorizedTypes[record.MemorizedFlag]
+	if !ok {
+		return Memorized{}, fmt.Errorf("%d: memorized transaction %q: unsupported type %q", record.Line, record.Payee, record.MemorizedFlag)
+	}
+	m := Memorized{
+		Line:          record.Line,
+		Type:          typ,
+		Payee:         record.Payee,
+		OriginalPayee: record.OriginalPayee,
+		Amount:        nonZero(record.AmountTCode),
+		ToAccount:     record.ToAccount,
+		Category:      record.Category,
+		Class:         record.Class,
+		ClearedStatus: record.ClearedStatus,
+		Memo:          record.Memo,
+		Address:       record.Address,
+		Amortization:  amortizationOf(record.Amortization),
+	}
+	for _, line := range record.Split {
+		m.Split = append(m.Split, Split{
+			Line:     line.Line,
+			Account:  line.Account,
+			Amount:   line.AmountOrZero(),
+			Category: line.Category,
+			Class:    line.Class,
+			Memo:     line.Memo,
+		})
+	}
+	return m, nil
+}
+
+// Prices returns the price history, sorted by ticker and then date.
+func Prices(records []*transaction.Record) []Price {
+	var prices []Price
+	for _, record := range records {
+		prices = append(prices, priceOf(record))
+	}
+	sort.SliceStable(prices, func(i, j int) bool {
+		if prices[i].Ticker != prices[j].Ticker {
+			return prices[i].Ticker < prices[j].Ticker
+		}
+		return prices[i].Date < prices[j].Date
+	})
+	return prices
+}
+
+func priceOf(record *transaction.Record) Price {
+	return Price{Line: record.Line, Ticker: record.Ticker, Date: record.Date, Price: record.Price}
+}
+
+// Transactions returns the bank and investment transactions, in the order they were read.
+// The splits of the bank transactions are normalized.
+func Transactions(r *reader.Reader) []Transaction {
+	var transactions []Transaction
+	for _, t := range transformer.NormalizeSplits(r.Transactions) {
+		transactions = append(transactions, transactionOf(t))
+	}
+	for _, record := range r.Investments {
+		transactions = append(transactions, investmentOf(record))
+	}
+	// keep the transactions in the order they were read
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Line < transactions[j].Line
+	})
+	return transactions
+}
+
+func transactionOf(t *transformer.Transaction) Transaction {
+	xact := Transaction{
+		Line:          t.Line,
+		Type:          accountTypes[t.Type],
+		Account:       t.Account,
+		ClearedStatus: t.ClearedStatus,
+		Date:          t.Date,
+		Memo:          t.Memo,
+		Payee:         t.Payee,
+		OriginalPayee: t.OriginalPayee,
+		RefNo:         t.RefNo,
+	}
+	amount := t.Amount
+	xact.Amount = &amount
+	for _, line := range t.Split {
+		xact.Split = append(xact.Split, Split{
+			Line:     line.Line,
+			Account:  line.Account,
+			Amount:   line.Amount,
+			Category: line.Category,
+			Class:    line.Class,
+			Memo:     line.Memo,
+		})
+	}
+	return xact
+}
+
+func investmentOf(record *investment.Record) Transaction {
+	total := record.Amount
+	if total.IsZero() {
+		total = record.AmountUCode
+	}
+	return Transaction{
+		Line:            record.Line,
+		Type:            "investment",
+		Date:            record.Date,
+		Account:         record.Account,
+		Category:        record.Category,
+		Class:           record.Class,
+		ClearedStatus:   record.ClearedStatus,
+		Memo:            record.Memo,
+		Payee:           record.Payee,
+		OriginalPayee:   record.OriginalPayee,
+		Action:          string(record.Action),
+		Security:        record.Security,
+		Shares:          nonZero(record.Shares),
+		Price:           nonZero(record.Price),
+		Commission:      nonZero(record.Commission),
+		Total:           nonZero(total),
+		TransferAccount: record.TransferAccount,
+		TransferAmount:  nonZero(record.TransferAmount),
+	}
+}
+
+// amortizationOf returns the amortization fields in order, without the
+// empty fields at the end. The first payment date is yyyy/mm/dd.
+func amortizationOf(a *transaction.Amortization) []string {
+	if a == nil {
+		return nil
+	}
+	fields := []string{a.FirstPaymentDate}
+	for _, amount := range []decimal.Decimal{a.Years, a.PaymentsMade, a.PeriodsPerYear, a.InterestRate, a.CurrentBalance, a.OriginalAmount} {
+		if amount == (decimal.Decimal{}) {
+			fields = append(fields, "")
+		} else {
+			fields = append(fields, amount.String())
+		}
+	}
+	for len(fields) != 0 && fields[len(fields)-1] == "" {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) == 0 {
+		// a loan whose fields are all zero
+		return []string{""}
+	}
+	return fields
+}
+
+// EncodeAccounts writes the accounts document.
+func EncodeAccounts(w io.Writer, section *account.Section) error {
+	var data struct {
+		Accounts []Account `json:"accounts"`
+	}
+	var err error
+	if data.Accounts, err = Accounts(section); err != nil {
+		return err
+	}
+	return encode(w, data)
+}
+
+// EncodeCategories writes the categories document.
+// If nested is true, subcategories are written as the children of their parent.
+func EncodeCategories(w io.Writer, section *category.Section, nested bool) error {
+	if nested {
+		var data struct {
+			Categories []*CategoryNode `json:"categories"`
+		}
+		data.Categories = CategoryTree(section)
+		return encode(w, data)
+	}
+	var data struct {
+		Categories []Category `json:"categories"`
+	}
+	data.Categories = Categories(section)
+	return encode(w, data)
+}
+
+// EncodeClasses writes the classes document.
+func EncodeClasses(w io.Writer, section *class.Section) error {
+	var data struct {
+		Classes []Class `json:"classes"`
+	}
+	data.Classes = Classes(section)
+	return encode(w, data)
+}
+
+// EncodeSecurities writes the securities document.
+func EncodeSecurities(w io.Writer, section *security.Section) error {
+	var data struct {
+		Securities []Security `json:"securities"`
+	}
+	data.Securities = Securities(section)
+	return encode(w, data)
+}
+
+// EncodeTags writes the tags document.
+func EncodeTags(w io.Writer, section *tag.Section) error {
+	var data struct {
+		Tags []Tag `json:"tags"`
+	}
+	data.Tags = Tags(section)
+	return encode(w, data)
+}
+
+// EncodeMemorized writes the memorized transactions document.
+func EncodeMemorized(w io.Writer, records []*transaction.Record) error {
+	var data struct {
+		Memorized []Memorized `json:"memorized"`
+	}
+	var err error
+	if data.Memorized, err = MemorizedTransactions(records); err != nil {
+		return err
+	}
+	return encode(w, data)
+}
+
+// EncodePrices writes the prices document.
+func EncodePrices(w io.Writer, records []*transaction.Record) error {
+	var data struct {
+		Prices []Price `json:"prices"`
+	}
+	data.Prices = Prices(records)
+	return encode(w, data)
+}
+
+// EncodeTransactions writes the transactions document.
+func EncodeTransactions(w io.Writer, r *reader.Reader) error {
+	var data struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	data.Transactions = Transactions(r)
+	return encode(w, data)
+}
+
+// encode writes the data as indented JSON.
+func encode(w io.Writer, data interface{}) error {
+	buf, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// nonZero returns nil for a zero amount so that it is omitted from the JSON.
+func nonZero(amount decimal.Decimal) *decimal.Decimal {
+	if amount.IsZero() {
+		return nil
+	}
+	return &amount
+}
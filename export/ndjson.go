@@ -0,0 +1,179 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/account"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/class"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/reader/tag"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+)
+
+// The kinds of the lines written by NDJSON.
+const (
+	KindAccount     = "account"
+	KindCategory    = "category"
+	KindClass       = "class"
+	KindDiagnostic  = "diagnostic"
+	KindMemorized   = "memorized"
+	KindPrice       = "price"
+	KindSecurity    = "security"
+	KindTag         = "tag"
+	KindTransaction = "transaction"
+)
+
+// NDJSON writes records as newline-delimited JSON.
+// Each record is written on its own line as soon as it is encoded,
+// with a "kind" field that says what the record is. The fields
+// are the same as in the documents written by the other encoders.
+type NDJSON struct {
+	w      io.Writer
+	Counts map[string]int // number of lines written for each kind
+}
+
+// NewNDJSON returns an encoder that writes to w.
+func NewNDJSON(w io.Writer) *NDJSON {
+	return &NDJSON{w: w, Counts: make(map[string]int)}
+}
+
+// Encode writes a record returned by reader.Decoder, or a reader.Diagnostic.
+func (e *NDJSON) Encode(record interface{}) error {
+	kind, v, err := lineOf(record)
+	if err != nil {
+		return err
+	}
+	return e.write(kind, v)
+}
+
+// Stream reads every record from the decoder and writes it to w.
+// In recovery mode, diagnostics are written as they are found,
+// and a record that can't be encoded is written as a diagnostic.
+func Stream(w io.Writer, d *reader.Decoder) (*NDJSON, error) {
+	e := NewNDJSON(w)
+	var reported int
+	for {
+		record, err := d.Next()
+		for _, diagnostic := range d.Diagnostics()[reported:] {
+			if err := e.Encode(diagnostic); err != nil {
+				return e, err
+			}
+			reported++
+		}
+		if err == io.EOF {
+			return e, nil
+		} else if err != nil {
+			return e, err
+		}
+		kind, v, err := lineOf(record)
+		if err != nil {
+			if !d.Recover() {
+				return e, err
+			}
+			kind, v = KindDiagnostic, encodeDiagnostic(record)
+		}
+		if err := e.write(kind, v); err != nil {
+			return e, err
+		}
+	}
+}
+
+// lineOf returns the kind and the value of the line for the record.
+func lineOf(record interface{}) (string, interface{}, error) {
+	switch record := record.(type) {
+	case *account.Record:
+		account, err := accountOf(record)
+		return KindAccount, account, err
+	case *category.Record:
+		return KindCategory, categoryOf(record), nil
+	case *class.Record:
+		return KindClass, classOf(record), nil
+	case *investment.Record:
+		return KindTransaction, investmentOf(record), nil
+	case *security.Record:
+		return KindSecurity, securityOf(record), nil
+	case *tag.Record:
+		return KindTag, tagOf(record), nil
+	case *transaction.Record:
+		switch record.Type {
+		case "Memorized":
+			memorized, err := memorizedOf(record)
+			return KindMemorized, memorized, err
+		case "Prices":
+			return KindPrice, priceOf(record), nil
+		}
+		return KindTransaction, transactionOf(transformer.NormalizeSplits([]*transaction.Record{record})[0]), nil
+	case reader.Diagnostic:
+		return KindDiagnostic, record, nil
+	}
+	return "", nil, fmt.Errorf("ndjson: unsupported record type %T", record)
+}
+
+// encodeDiagnostic returns the diagnostic for a record that lineOf can't encode.
+// The record is dropped, as the decoder drops a record it can't parse.
+func encodeDiagnostic(record interface{}) reader.Diagnostic {
+	diagnostic := reader.Diagnostic{Severity: reader.SeverityError}
+	switch record := record.(type) {
+	case *account.Record:
+		diagnostic.Line, diagnostic.Col, diagnostic.Section, diagnostic.Field = record.Line, record.Col, "account", "type"
+		diagnostic.Message = fmt.Sprintf("unsupported account type %q: record dropped", record.Type)
+	case *transaction.Record:
+		diagnostic.Line, diagnostic.Col, diagnostic.Section, diagnostic.Field = record.Line, record.Col, "transaction", "memorized"
+		diagnostic.Message = fmt.Sprintf("unsupported memorized transaction type %q: record dropped", record.MemorizedFlag)
+	default:
+		diagnostic.Message = fmt.Sprintf("unsupported record type %T: record dropped", record)
+	}
+	return diagnostic
+}
+
+// write writes the value as a single line with the kind as the first field.
+func (e *NDJSON) write(kind string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var line bytes.Buffer
+	line.WriteString(`{"kind":"` + kind + `"`)
+	if fields := bytes.TrimPrefix(buf, []byte("{")); len(fields) > 1 {
+		line.WriteByte(',')
+		line.Write(fields)
+	} else {
+		line.WriteByte('}')
+	}
+	line.WriteByte('\n')
+	if _, err := e.w.Write(line.Bytes()); err != nil {
+		return err
+	}
+	e.Counts[kind]++
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"bytes"
+	"github.com/mdhender/qif2json/reader"
+	"strings"
+	"testing"
+)
+
+// unencodable has an account and a memorized transaction with types
+// that the reader accepts but the JSON has no name for.
+const unencodable = "!Account\nNChecking\nTBank\n^\nNOdd\nTXyz\n^\n" +
+	"!Type:Memorized\nKZ\nPGas Co\nT-45.00\n^\n" +
+	"!Account\nNChecking\nTBank\n^\n!Type:Bank\nD1/1/2021\nT1.00\n^\n"
+
+func TestStreamRecover(t *testing.T) {
+	var buf bytes.Buffer
+	d := reader.NewDecoder(strings.NewReader(unencodable), reader.Options{Recover: true})
+	if _, err := Stream(&buf, d); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	want := []string{
+		`{"kind":"account","type":"bank","name":"Checking"}`,
+		`{"kind":"diagnostic","line":5,"col":1,"section":"account","field":"type","severity":"error","message":"unsupported account type \"Xyz\": record dropped"}`,
+		`{"kind":"diagnostic","line":9,"col":1,"section":"transaction","field":"memorized","severity":"error","message":"unsupported memorized transaction type \"Z\": record dropped"}`,
+		`{"kind":"transaction","line":18,"type":"bank","date":"2021/01/01","account":"Checking","amount":"1.00","lines":[{"line":18,"amount":"1.00"}]}`,
+	}
+	if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Stream: got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestStreamStopsAtFirstError(t *testing.T) {
+	var buf bytes.Buffer
+	d := reader.NewDecoder(strings.NewReader(unencodable), reader.Options{})
+	if _, err := Stream(&buf, d); err == nil || !strings.Contains(err.Error(), `unsupported account type "Xyz"`) {
+		t.Errorf("Stream: got %v, want the unsupported account type", err)
+	}
+}
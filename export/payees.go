@@ -0,0 +1,76 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+)
+
+// PayeeChange reports the rule that was applied to the payee of a record.
+type PayeeChange struct {
+	Line          int    `json:"line,omitempty"`
+	Record        string `json:"record"` // transaction, memorized or investment
+	Date          string `json:"date,omitempty"`
+	Account       string `json:"account,omitempty"`
+	OriginalPayee string `json:"original_payee"`
+	Payee         string `json:"payee"`
+	Rule          int    `json:"rule,omitempty"` // the number of the rule, starting at 1, or 0 if none matched
+	Match         string `json:"match,omitempty"`
+	Pattern       string `json:"pattern,omitempty"`
+}
+
+// PayeeChanges returns the changes in the order of the records.
+func PayeeChanges(changes []*transformer.PayeeChange, rules *transformer.PayeeRules) []PayeeChange {
+	numbers := make(map[*transformer.PayeeRule]int)
+	for i, rule := range rules.Rules {
+		numbers[rule] = i + 1
+	}
+	var list []PayeeChange
+	for _, c := range changes {
+		change := PayeeChange{
+			Line:          c.Line,
+			Record:        c.Record,
+			Date:          c.Date,
+			Account:       c.Account,
+			OriginalPayee: c.Original,
+			Payee:         c.Payee,
+		}
+		if c.Rule != nil {
+			change.Rule, change.Match, change.Pattern = numbers[c.Rule], string(c.Rule.Match), c.Rule.Pattern
+		}
+		list = append(list, change)
+	}
+	return list
+}
+
+// EncodePayeeChanges writes the payee report document.
+func EncodePayeeChanges(w io.Writer, changes []*transformer.PayeeChange, rules *transformer.PayeeRules) error {
+	var data struct {
+		Payees []PayeeChange `json:"payees"`
+	}
+	data.Payees = PayeeChanges(changes, rules)
+	return encode(w, data)
+}
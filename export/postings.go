@@ -0,0 +1,91 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+)
+
+// Entry is a transaction as balanced double-entry postings.
+type Entry struct {
+	Line       int              `json:"line,omitempty"`
+	Date       string           `json:"date,omitempty"`
+	Account    string           `json:"account,omitempty"`
+	Payee      string           `json:"payee,omitempty"`
+	RefNo      string           `json:"ref_no,omitempty"`
+	Amount     decimal.Decimal  `json:"amount"`
+	Unbalanced bool             `json:"unbalanced,omitempty"`
+	Difference *decimal.Decimal `json:"difference,omitempty"`
+	Postings   []Posting        `json:"postings"`
+}
+
+// Posting is a change to the balance of an account or category.
+// The line is the line of the split, and is omitted for the posting to the account.
+type Posting struct {
+	Line     int             `json:"line,omitempty"`
+	Kind     string          `json:"kind"`
+	Account  string          `json:"account,omitempty"`
+	Category string          `json:"category,omitempty"`
+	Amount   decimal.Decimal `json:"amount"`
+	Class    string          `json:"class,omitempty"`
+	Memo     string          `json:"memo,omitempty"`
+}
+
+// Entries returns the entries in the order of the transactions.
+func Entries(entries []*transformer.Entry) []Entry {
+	var list []Entry
+	for _, e := range entries {
+		entry := Entry{
+			Line:       e.Transaction.Line,
+			Date:       e.Transaction.Date,
+			Account:    e.Transaction.Account,
+			Payee:      e.Transaction.Payee,
+			RefNo:      e.Transaction.RefNo,
+			Amount:     e.Transaction.Amount,
+			Unbalanced: e.Unbalanced,
+			Difference: nonZero(e.Difference),
+		}
+		for _, p := range e.Postings {
+			posting := Posting{Kind: string(p.Kind), Account: p.Account, Category: p.Category, Amount: p.Amount, Class: p.Class, Memo: p.Memo}
+			if p.Split != nil {
+				posting.Line = p.Split.Line
+			}
+			entry.Postings = append(entry.Postings, posting)
+		}
+		list = append(list, entry)
+	}
+	return list
+}
+
+// EncodePostings writes the postings document.
+func EncodePostings(w io.Writer, entries []*transformer.Entry) error {
+	var data struct {
+		Entries []Entry `json:"entries"`
+	}
+	data.Entries = Entries(entries)
+	return encode(w, data)
+}
@@ -0,0 +1,71 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+	"strings"
+)
+
+// WriteReconciliation writes a plain text report of the running balance
+// of each account and how it compares to the statement balance.
+func WriteReconciliation(w io.Writer, balances []*transformer.AccountBalance) error {
+	bw := bufio.NewWriter(w)
+	for i, a := range balances {
+		if i != 0 {
+			fmt.Fprintln(bw)
+		}
+		name := a.Account
+		if name == "" {
+			name = "(no account)"
+		}
+		if a.Type != "" {
+			name += " (" + a.Type + ")"
+		}
+		fmt.Fprintln(bw, name)
+		for _, b := range a.Balances {
+			payee := strings.Join(strings.Fields(b.Transaction.Payee), " ")
+			if len([]rune(payee)) > 30 {
+				payee = string([]rune(payee)[:30])
+			}
+			fmt.Fprintf(bw, "  %-10s  %-1s  %-30s  %12s  %12s\n", b.Transaction.Date, b.Transaction.ClearedStatus, payee, b.Amount, b.Balance)
+		}
+		fmt.Fprintf(bw, "  balance %s: reconciled %s, cleared %s, uncleared %s\n",
+			a.Balance, a.Breakdown.Reconciled, a.Breakdown.Cleared, a.Breakdown.Uncleared)
+		if s := a.Statement; s != nil {
+			date := s.Date
+			if date == "" {
+				date = "(no date)"
+			}
+			fmt.Fprintf(bw, "  statement balance %s on %s: computed %s, discrepancy %s\n", s.Balance, date, s.Computed, s.Discrepancy)
+			fmt.Fprintf(bw, "    reconciled %s, cleared %s, uncleared %s, discrepancy of the cleared balance %s\n",
+				s.Breakdown.Reconciled, s.Breakdown.Cleared, s.Breakdown.Uncleared, s.ClearedDiscrepancy)
+		}
+	}
+	return bw.Flush()
+}
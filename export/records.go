@@ -0,0 +1,334 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"fmt"
+	"github.com/mdhender/qif2json/date"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/account"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/class"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/reader/tag"
+	"github.com/mdhender/qif2json/reader/transaction"
+)
+
+// Records converts the document back into the records of a QIF file.
+// It is the reverse of NewDocument, so the result can be written with qif.Write.
+// The IDs in the document are ignored. Synthesized categories are dropped
+// since they weren't in the QIF file.
+//
+// It returns an error for the first record that can't be converted,
+// such as an account with an unknown type or a transaction with an invalid date.
+func (doc *Document) Records() (*reader.Reader, error) {
+	r := reader.Reader{
+		Accounts:   &account.Section{},
+		Categories: &category.Section{},
+		Classes:    &class.Section{},
+		Securities: &security.Section{},
+		Tags:       &tag.Section{},
+	}
+
+	quickenAccountTypes := make(map[string]string)
+	for quicken, typ := range accountTypes {
+		quickenAccountTypes[typ] = quicken
+	}
+	sectionTypes := make(map[string]string) // account name to the type of its transaction section
+	for i, a := range doc.Accounts {
+		if a.Name == "" {
+			return nil, fmt.Errorf("accounts[%d]: name: missing field", i)
+		}
+		typ, ok := quickenAccountTypes[a.Type]
+		if !ok {
+			return nil, fmt.Errorf("accounts[%d]: %q: unsupported account type %q", i, a.Name, a.Type)
+		}
+		statementDate, err := checkDate(a.StatementBalanceDate)
+		if err != nil {
+			return nil, fmt.Errorf("accounts[%d]: %q: statement_date: %w", i, a.Name, err)
+		}
+		r.Accounts.Records = append(r.Accounts.Records, &account.Record{
+			Name:                 a.Name,
+			Type:                 typ,
+			Description:          a.Description,
+			CreditLimit:          value(a.CreditLimit),
+			StatementBalance:     value(a.StatementBalance),
+			StatementBalanceDate: statementDate,
+		})
+		switch typ {
+		case "Bank", "Cash", "CCard", "Invst", "Oth A", "Oth L":
+			sectionTypes[a.Name] = typ
+		case "Port", "Mutual", "401(k)/403(b)":
+			sectionTypes[a.Name] = "Invst"
+		}
+	}
+
+	for i, c := range doc.Categories {
+		if c.Synthesized {
+			continue
+		} else if c.Name == "" {
+			return nil, fmt.Errorf("categories[%d]: name: missing field", i)
+		}
+		r.Categories.Records = append(r.Categories.Records, &category.Record{
+			Name:         c.Name,
+			Description:  c.Description,
+			IsIncome:     c.Income,
+			IsTaxRelated: c.TaxRelated,
+			TaxSchedule:  c.TaxSchedule,
+		})
+	}
+
+	for i, c := range doc.Classes {
+		if c.Name == "" {
+			return nil, fmt.Errorf("classes[%d]: name: missing field", i)
+		}
+		r.Classes.Records = append(r.Classes.Records, &class.Record{Name: c.Name, Description: c.Description})
+	}
+
+	for i, s := range doc.Securities {
+		if s.Name == "" {
+			return nil, fmt.Errorf("securities[%d]: name: missing field", i)
+		}
+		r.Securities.Records = append(r.Securities.Records, &security.Record{
+			Name:        s.Name,
+			Ticker:      s.Ticker,
+			Type:        s.Type,
+			Description: s.Description,
+			Risk:        s.Risk,
+		})
+	}
+
+	for i, t := range doc.Tags {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tags[%d]: name: missing field", i)
+		}
+		r.Tags.Records = append(r.Tags.Records, &tag.Record{Name: t.Name, Description: t.Description})
+	}
+
+	for i, t := range doc.Transactions {
+		d, err := checkDate(t.Date)
+		if err != nil {
+			return nil, fmt.Errorf("transactions[%d]: date: %w", i, err)
+		} else if d == "" {
+			return nil, fmt.Errorf("transactions[%d]: date: missing field", i)
+		}
+		if t.Type == "investment" {
+			action, err := investment.ParseAction(t.Action)
+			if err != nil {
+				return nil, fmt.Errorf("transactions[%d]: action: %w", i, err)
+			}
+			r.Investments = append(r.Investments, &investment.Record{
+				Line:            t.Line,
+				Account:         t.Account,
+				Action:          action,
+				Amount:          value(t.Total),
+				Category:        t.Category,
+				Class:           t.Class,
+				ClearedStatus:   t.ClearedStatus,
+				Commission:      value(t.Commission),
+				Date:            d,
+				Memo:            t.Memo,
+				Payee:           t.Payee,
+				Price:           value(t.Price),
+				Security:        t.Security,
+				Shares:          value(t.Shares),
+				TransferAccount: t.TransferAccount,
+				TransferAmount:  value(t.TransferAmount),
+			})
+			continue
+		}
+		if len(t.Split) == 0 {
+			return nil, fmt.Errorf("transactions[%d]: lines: missing field", i)
+		}
+		// the type from older documents is missing, so fall back to the account list
+		typ, ok := quickenAccountTypes[t.Type], true
+		if t.Type == "" {
+			typ, ok = sectionTypes[t.Account]
+		}
+		if !ok {
+			return nil, fmt.Errorf("transactions[%d]: %q: type: missing field, and the account isn't in the accounts", i, t.Account)
+		}
+		switch typ {
+		case "Bank", "Cash", "CCard", "Oth A", "Oth L":
+		default:
+			return nil, fmt.Errorf("transactions[%d]: type: unsupported transaction type %q", i, t.Type)
+		}
+		record := unsplit(t.Split, t.Memo)
+		record.Line, record.Type, record.Account, record.Date = t.Line, typ, t.Account, d
+		record.ClearedStatus, record.Payee, record.RefNo = t.ClearedStatus, t.Payee, t.RefNo
+		r.Transactions = append(r.Transactions, record)
+	}
+
+	quickenMemorizedTypes := make(map[string]string)
+	for quicken, typ := range memorizedTypes {
+		quickenMemorizedTypes[typ] = quicken
+	}
+	for i, m := range doc.Memorized {
+		flag, ok := quickenMemorizedTypes[m.Type]
+		if !ok {
+			return nil, fmt.Errorf("memorized[%d]: unsupported type %q", i, m.Type)
+		}
+		record := transaction.Record{
+			Line:          m.Line,
+			Type:          "Memorized",
+			MemorizedFlag: flag,
+			Payee:         m.Payee,
+			AmountTCode:   value(m.Amount),
+			ToAccount:     m.ToAccount,
+			Category:      m.Category,
+			Class:         m.Class,
+			ClearedStatus: m.ClearedStatus,
+			Memo:          m.Memo,
+			Address:       m.Address,
+		}
+		var err error
+		if record.Amortization, err = amortization(m.Amortization); err != nil {
+			return nil, fmt.Errorf("memorized[%d]: amortization: %w", i, err)
+		}
+		for _, line := range m.Split {
+			amount := line.Amount
+			record.Split = append(record.Split, &transaction.Split{
+				Account:  line.Account,
+				Amount:   &amount,
+				Category: line.Category,
+				Class:    line.Class,
+				Memo:     line.Memo,
+			})
+		}
+		r.Memorized = append(r.Memorized, &record)
+	}
+
+	for i, p := range doc.Prices {
+		d, err := checkDate(p.Date)
+		if err != nil {
+			return nil, fmt.Errorf("prices[%d]: date: %w", i, err)
+		} else if d == "" {
+			return nil, fmt.Errorf("prices[%d]: date: missing field", i)
+		} else if p.Ticker == "" {
+			return nil, fmt.Errorf("prices[%d]: ticker: missing field", i)
+		}
+		r.Prices = append(r.Prices, &transaction.Record{Line: p.Line, Type: "Prices", Ticker: p.Ticker, Date: d, Price: p.Price})
+	}
+
+	return &r, nil
+}
+
+// FlattenCategoryTree returns the categories of a tree written by EncodeCategories
+// as a list, parents before children. The name of each category is its full path.
+func FlattenCategoryTree(roots []*CategoryNode) []Category {
+	var categories []Category
+	var walk func(nodes []*CategoryNode)
+	walk = func(nodes []*CategoryNode) {
+		for _, node := range nodes {
+			name := node.Path
+			if name == "" {
+				name = node.Name
+			}
+			categories = append(categories, Category{
+				Name:        name,
+				Description: node.Description,
+				Income:      node.Income,
+				TaxRelated:  node.TaxRelated,
+				TaxSchedule: node.TaxSchedule,
+				Synthesized: node.Synthesized,
+			})
+			walk(node.Children)
+		}
+	}
+	walk(roots)
+	return categories
+}
+
+// unsplit is the reverse of transformer.NormalizeSplits.
+// A transaction with a single split has its category or transfer
+// account on the transaction, unless both have a memo. Otherwise,
+// the splits are kept and the amount of the transaction is the
+// total of the splits.
+func unsplit(splits []Split, memo string) *transaction.Record {
+	var record transaction.Record
+	if len(splits) == 1 && (memo == "" || splits[0].Memo == "") {
+		split := splits[0]
+		record.AmountTCode, record.Category, record.Class, record.ToAccount = split.Amount, split.Category, split.Class, split.Account
+		record.Memo = memo
+		if record.Memo == "" {
+			record.Memo = split.Memo
+		}
+		return &record
+	}
+	record.Memo = memo
+	for _, line := range splits {
+		amount := line.Amount
+		record.AmountTCode = record.AmountTCode.Add(amount)
+		record.Split = append(record.Split, &transaction.Split{
+			Account:  line.Account,
+			Amount:   &amount,
+			Category: line.Category,
+			Class:    line.Class,
+			Memo:     line.Memo,
+		})
+	}
+	return &record
+}
+
+// checkDate returns the date in the yyyy/mm/dd form used by the records.
+// An empty date is returned unchanged.
+func checkDate(text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	return date.Default.Parse(text)
+}
+
+// amortization returns the amortization fields 1 to 7, or nil if there aren't any.
+func amortization(fields []string) (*transaction.Amortization, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	} else if len(fields) > 7 {
+		return nil, fmt.Errorf("more than 7 fields")
+	}
+	var a transaction.Amortization
+	var err error
+	if a.FirstPaymentDate, err = checkDate(fields[0]); err != nil {
+		return nil, err
+	}
+	for i, amount := range []*decimal.Decimal{&a.Years, &a.PaymentsMade, &a.PeriodsPerYear, &a.InterestRate, &a.CurrentBalance, &a.OriginalAmount} {
+		if i+1 < len(fields) {
+			if *amount, err = decimal.Parse(fields[i+1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &a, nil
+}
+
+// value returns the amount, or zero if it is nil.
+func value(amount *decimal.Decimal) decimal.Decimal {
+	if amount == nil {
+		return decimal.Decimal{}
+	}
+	return *amount
+}
@@ -0,0 +1,122 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/mdhender/qif2json/qif"
+	"github.com/mdhender/qif2json/reader"
+	"testing"
+)
+
+// TestRecordsRoundTrip checks that a document written as QIF with Records
+// and read back is the document that was written, as json2qif and qif2json do.
+func TestRecordsRoundTrip(t *testing.T) {
+	want, err := NewDocument(readFixture(t, "records.qif"), Metadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		accounts bool
+	}{
+		{"with accounts", true},
+		// json2qif given only the transactions file
+		{"without accounts", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc Document
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+			if !tc.accounts {
+				doc.Accounts = nil
+			}
+			r, err := doc.Records()
+			if err != nil {
+				t.Fatalf("records: %v", err)
+			}
+			var buf bytes.Buffer
+			if err := qif.Write(&buf, r, qif.WriteOptions{}); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			r, err = reader.ReadFrom(bytes.NewReader(buf.Bytes()), reader.Options{})
+			if err != nil {
+				t.Fatalf("read output: %v\n%s", err, buf.String())
+			}
+			got, err := NewDocument(r, Metadata{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// without accounts the QIF gains an account list, so only the transactions can be compared
+			var g, w interface{} = got, want
+			if !tc.accounts {
+				g, w = got.Transactions, want.Transactions
+			}
+			if g, w := linesCleared(t, g), linesCleared(t, w); g != w {
+				t.Errorf("document differs after the round trip\ngot  %s\nwant %s\n%s", g, w, buf.String())
+			}
+		})
+	}
+}
+
+// linesCleared returns the value as JSON without the line numbers,
+// since they depend on where the records are in the file.
+func linesCleared(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	var clear func(v interface{})
+	clear = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			delete(v, "line")
+			for _, e := range v {
+				clear(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				clear(e)
+			}
+		}
+	}
+	clear(value)
+	if data, err = json.Marshal(value); err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,418 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL dialect written by WriteSQL.
+type Dialect string
+
+const (
+	SQLite     Dialect = "sqlite"
+	PostgreSQL Dialect = "postgres"
+)
+
+// SQLOptions controls the output of WriteSQL.
+type SQLOptions struct {
+	Dialect Dialect // defaults to SQLite
+}
+
+// sqlTables is the schema, with the parent tables before the tables that refer to them.
+// The types are written for the dialect: BOOLEAN, DATE and NUMERIC are
+// INTEGER, TEXT and TEXT in SQLite. SQLite has no boolean or date type, and
+// it stores a NUMERIC with a fraction as a binary floating point number, so
+// amounts are stored as the text of the exact decimal, e.g. '1259.95'.
+// Use CAST(amount AS NUMERIC) to sum or compare them.
+var sqlTables = []struct {
+	name    string
+	columns []string
+}{
+	{"accounts", []string{
+		"id TEXT PRIMARY KEY",
+		"name TEXT NOT NULL",
+		"type TEXT", // NULL if the account was referenced but not in the account list
+		"description TEXT",
+		"credit_limit NUMERIC",
+		"statement_balance NUMERIC",
+		"statement_date DATE",
+	}},
+	{"categories", []string{
+		"id TEXT PRIMARY KEY",
+		"parent_id TEXT REFERENCES categories (id)",
+		"name TEXT NOT NULL",
+		"description TEXT",
+		"income BOOLEAN NOT NULL",
+		"tax_related BOOLEAN NOT NULL",
+		"tax_schedule TEXT",
+		"synthesized BOOLEAN NOT NULL", // true if the category wasn't in the category list
+	}},
+	{"classes", []string{
+		"id TEXT PRIMARY KEY",
+		"name TEXT NOT NULL",
+		"description TEXT",
+	}},
+	{"securities", []string{
+		"id TEXT PRIMARY KEY",
+		"name TEXT NOT NULL",
+		"ticker TEXT",
+		"type TEXT",
+		"description TEXT",
+		"risk TEXT",
+	}},
+	{"tags", []string{
+		"id TEXT PRIMARY KEY",
+		"name TEXT NOT NULL",
+		"description TEXT",
+	}},
+	{"transactions", []string{
+		"id TEXT PRIMARY KEY",
+		"line INTEGER",
+		"type TEXT",
+		"date DATE",
+		"account_id TEXT REFERENCES accounts (id)",
+		"payee TEXT",
+		"ref_no TEXT",
+		"cleared_status TEXT",
+		"memo TEXT",
+		"category_id TEXT REFERENCES categories (id)",
+		"class_id TEXT REFERENCES classes (id)",
+		"action TEXT",
+		"security_id TEXT REFERENCES securities (id)",
+		"shares NUMERIC",
+		"price NUMERIC",
+		"commission NUMERIC",
+		"total NUMERIC",
+		"transfer_account_id TEXT REFERENCES accounts (id)",
+		"transfer_amount NUMERIC",
+	}},
+	{"splits", []string{
+		"transaction_id TEXT NOT NULL REFERENCES transactions (id)",
+		"seq INTEGER NOT NULL",
+		"line INTEGER",
+		"account_id TEXT REFERENCES accounts (id)",
+		"category_id TEXT REFERENCES categories (id)",
+		"class_id TEXT REFERENCES classes (id)",
+		"amount NUMERIC NOT NULL",
+		"memo TEXT",
+		"PRIMARY KEY (transaction_id, seq)",
+	}},
+	{"memorized", []string{
+		"id TEXT PRIMARY KEY",
+		"line INTEGER",
+		"type TEXT NOT NULL",
+		"payee TEXT",
+		"amount NUMERIC",
+		"to_account_id TEXT REFERENCES accounts (id)",
+		"category_id TEXT REFERENCES categories (id)",
+		"class_id TEXT REFERENCES classes (id)",
+		"cleared_status TEXT",
+		"memo TEXT",
+		"address TEXT",            // the address lines, separated by line breaks
+		"first_payment_date DATE", // the amortization fields, NULL if not set
+		"years NUMERIC",
+		"payments_made NUMERIC",
+		"periods_per_year NUMERIC",
+		"interest_rate NUMERIC",
+		"current_balance NUMERIC",
+		"original_amount NUMERIC",
+	}},
+	{"memorized_splits", []string{
+		"memorized_id TEXT NOT NULL REFERENCES memorized (id)",
+		"seq INTEGER NOT NULL",
+		"account_id TEXT REFERENCES accounts (id)",
+		"category_id TEXT REFERENCES categories (id)",
+		"class_id TEXT REFERENCES classes (id)",
+		"amount NUMERIC NOT NULL",
+		"memo TEXT",
+		"PRIMARY KEY (memorized_id, seq)",
+	}},
+	{"prices", []string{
+		"id TEXT PRIMARY KEY",
+		"security_id TEXT NOT NULL REFERENCES securities (id)",
+		"ticker TEXT NOT NULL",
+		"date DATE NOT NULL",
+		"price NUMERIC NOT NULL",
+	}},
+}
+
+// WriteSQL writes a script that creates the tables and inserts every record.
+// The keys are the IDs of the combined document. Accounts, categories, classes
+// and securities that are referred to but weren't listed in the QIF file are
+// inserted too, so that every foreign key refers to a row.
+func WriteSQL(w io.Writer, r *reader.Reader, opts SQLOptions) error {
+	if opts.Dialect == "" {
+		opts.Dialect = SQLite
+	} else if opts.Dialect != SQLite && opts.Dialect != PostgreSQL {
+		return fmt.Errorf("sql: unsupported dialect %q", opts.Dialect)
+	}
+	doc, err := NewDocument(r, Metadata{})
+	if err != nil {
+		return err
+	}
+	sw := sqlWriter{w: bufio.NewWriter(w), dialect: opts.Dialect}
+
+	if sw.dialect == SQLite {
+		sw.line("PRAGMA foreign_keys = ON;")
+	}
+	sw.line("BEGIN;")
+	for _, table := range sqlTables {
+		sw.line("")
+		sw.line("CREATE TABLE " + table.name + " (")
+		for i, column := range table.columns {
+			if sw.dialect == SQLite {
+				column = strings.NewReplacer(" BOOLEAN", " INTEGER", " DATE", " TEXT", " NUMERIC", " TEXT").Replace(column)
+			}
+			if i < len(table.columns)-1 {
+				column += ","
+			}
+			sw.line("  " + column)
+		}
+		sw.line(");")
+	}
+
+	// the referenced records that weren't listed are added to the lists
+	accounts := make(map[string]bool)
+	for _, a := range doc.Accounts {
+		accounts[AccountID(a.Name)] = true
+	}
+	account := func(name string) string {
+		if id := AccountID(name); id != "" && !accounts[id] {
+			accounts[id] = true
+			doc.Accounts = append(doc.Accounts, Account{Name: name})
+		}
+		return AccountID(name)
+	}
+	// a category that isn't listed is added with its parents, so that every level is linked
+	tree := categoryTree(r.Categories)
+	category := func(name string) string {
+		if name != "" {
+			tree.Add(name, false)
+		}
+		return CategoryID(name)
+	}
+	classes := make(map[string]bool)
+	for _, c := range doc.Classes {
+		classes[c.ID] = true
+	}
+	class := func(name string) string {
+		if id := ClassID(name); id != "" && !classes[id] {
+			classes[id] = true
+			doc.Classes = append(doc.Classes, Class{ID: id, Name: name})
+		}
+		return ClassID(name)
+	}
+	securities := make(map[string]bool)
+	for _, s := range doc.Securities {
+		securities[s.ID] = true
+	}
+	security := func(id, name string) string {
+		if id != "" && !securities[id] {
+			securities[id] = true
+			doc.Securities = append(doc.Securities, Security{ID: id, Name: name})
+		}
+		return id
+	}
+	for _, t := range doc.Transactions {
+		account(t.Account)
+		account(t.TransferAccount)
+		category(t.Category)
+		class(t.Class)
+		security(t.SecurityID, t.Security)
+		for _, s := range t.Split {
+			account(s.Account)
+			category(s.Category)
+			class(s.Class)
+		}
+	}
+	for _, m := range doc.Memorized {
+		account(m.ToAccount)
+		category(m.Category)
+		class(m.Class)
+		for _, s := range m.Split {
+			account(s.Account)
+			category(s.Category)
+			class(s.Class)
+		}
+	}
+	for _, p := range doc.Prices {
+		security(p.SecurityID, p.Ticker)
+	}
+	doc.Categories = categoryList(tree)
+
+	// a name listed twice is only inserted once
+	inserted := make(map[string]bool)
+	first := func(id string) bool {
+		if inserted[id] {
+			return false
+		}
+		inserted[id] = true
+		return true
+	}
+
+	sw.line("")
+	for _, a := range doc.Accounts {
+		if !first(AccountID(a.Name)) {
+			continue
+		}
+		sw.insert("accounts", sw.text(AccountID(a.Name)), sw.text(a.Name), sw.text(a.Type), sw.text(a.Description),
+			sw.number(a.CreditLimit), sw.number(a.StatementBalance), sw.date(a.StatementBalanceDate))
+	}
+	for _, c := range doc.Categories {
+		if !first(c.ID) {
+			continue
+		}
+		sw.insert("categories", sw.text(c.ID), sw.text(c.ParentID), sw.text(c.Name), sw.text(c.Description),
+			sw.boolean(c.Income), sw.boolean(c.TaxRelated), sw.text(c.TaxSchedule), sw.boolean(c.Synthesized))
+	}
+	for _, c := range doc.Classes {
+		if !first(c.ID) {
+			continue
+		}
+		sw.insert("classes", sw.text(c.ID), sw.text(c.Name), sw.text(c.Description))
+	}
+	for _, s := range doc.Securities {
+		if !first(s.ID) {
+			continue
+		}
+		sw.insert("securities", sw.text(s.ID), sw.text(s.Name), sw.text(s.Ticker), sw.text(s.Type), sw.text(s.Description), sw.text(s.Risk))
+	}
+	for _, t := range doc.Tags {
+		if !first(t.ID) {
+			continue
+		}
+		sw.insert("tags", sw.text(t.ID), sw.text(t.Name), sw.text(t.Description))
+	}
+	for _, t := range doc.Transactions {
+		sw.insert("transactions", sw.text(t.ID), strconv.Itoa(t.Line), sw.text(t.Type), sw.date(t.Date),
+			sw.text(t.AccountID), sw.text(t.Payee), sw.text(t.RefNo), sw.text(t.ClearedStatus), sw.text(t.Memo),
+			sw.text(CategoryID(t.Category)), sw.text(ClassID(t.Class)), sw.text(t.Action), sw.text(t.SecurityID),
+			sw.number(t.Shares), sw.number(t.Price), sw.number(t.Commission), sw.number(t.Total),
+			sw.text(t.TransferAccountID), sw.number(t.TransferAmount))
+		for i, s := range t.Split {
+			sw.insert("splits", sw.text(t.ID), strconv.Itoa(i+1), strconv.Itoa(s.Line), sw.text(s.AccountID),
+				sw.text(s.CategoryID), sw.text(s.ClassID), sw.numeric(s.Amount.String()), sw.text(s.Memo))
+		}
+	}
+	for _, m := range doc.Memorized {
+		// the amortization fields are the date and six numbers, without the empty fields at the end
+		amortization := make([]string, 7)
+		copy(amortization, m.Amortization)
+		values := []string{sw.text(m.ID), strconv.Itoa(m.Line), sw.text(m.Type), sw.text(m.Payee), sw.number(m.Amount),
+			sw.text(m.ToAccountID), sw.text(m.CategoryID), sw.text(m.ClassID), sw.text(m.ClearedStatus), sw.text(m.Memo),
+			sw.text(strings.Join(m.Address, "\n")), sw.date(amortization[0])}
+		for _, number := range amortization[1:] {
+			values = append(values, sw.numeric(number))
+		}
+		sw.insert("memorized", values...)
+		for i, s := range m.Split {
+			sw.insert("memorized_splits", sw.text(m.ID), strconv.Itoa(i+1), sw.text(s.AccountID),
+				sw.text(s.CategoryID), sw.text(s.ClassID), sw.numeric(s.Amount.String()), sw.text(s.Memo))
+		}
+	}
+	for _, p := range doc.Prices {
+		sw.insert("prices", sw.text(p.ID), sw.text(p.SecurityID), sw.text(p.Ticker), sw.date(p.Date), sw.numeric(p.Price.String()))
+	}
+
+	sw.line("")
+	sw.line("COMMIT;")
+	if sw.err != nil {
+		return sw.err
+	}
+	return sw.w.Flush()
+}
+
+// sqlWriter writes the statements of the script.
+// The first error is saved and later writes are ignored.
+type sqlWriter struct {
+	w       *bufio.Writer
+	dialect Dialect
+	err     error
+}
+
+func (sw *sqlWriter) line(text string) {
+	if sw.err == nil {
+		_, sw.err = sw.w.WriteString(text + "\n")
+	}
+}
+
+// insert writes an INSERT statement with the values, which must already be SQL literals.
+func (sw *sqlWriter) insert(table string, values ...string) {
+	sw.line("INSERT INTO " + table + " VALUES (" + strings.Join(values, ", ") + ");")
+}
+
+// text returns the string literal of the text, or NULL if it is empty.
+func (sw *sqlWriter) text(text string) string {
+	if text == "" {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+}
+
+// number returns the literal of the number, or NULL if it wasn't set.
+func (sw *sqlWriter) number(d *decimal.Decimal) string {
+	if d == nil {
+		return "NULL"
+	}
+	return sw.numeric(d.String())
+}
+
+// numeric returns the literal of the text of a decimal number, or NULL if it is empty.
+// In SQLite, the number is a string so that it isn't rounded to a binary fraction.
+func (sw *sqlWriter) numeric(text string) string {
+	if text == "" {
+		return "NULL"
+	} else if sw.dialect == SQLite {
+		return sw.text(text)
+	}
+	return text
+}
+
+// date returns the literal of a yyyy/mm/dd date, or NULL if it is empty.
+func (sw *sqlWriter) date(date string) string {
+	if date == "" {
+		return "NULL"
+	}
+	return sw.text(strings.ReplaceAll(date, "/", "-"))
+}
+
+func (sw *sqlWriter) boolean(b bool) string {
+	switch {
+	case sw.dialect == SQLite && b:
+		return "1"
+	case sw.dialect == SQLite:
+		return "0"
+	case b:
+		return "TRUE"
+	}
+	return "FALSE"
+}
@@ -0,0 +1,116 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"bytes"
+	"github.com/mdhender/qif2json/reader"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// readFixture reads a QIF file from the testdata directory.
+func readFixture(t *testing.T, name string) *reader.Reader {
+	t.Helper()
+	fd, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	r, err := reader.ReadFrom(fd, reader.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestWriteSQL(t *testing.T) {
+	r := readFixture(t, "sql.qif")
+	for _, tc := range []struct {
+		dialect Dialect
+		want    []string // statements that must be in the script
+	}{
+		{SQLite, []string{
+			"PRAGMA foreign_keys = ON;",
+			"  amount TEXT NOT NULL,",
+			"  income INTEGER NOT NULL,",
+			// the categories that aren't listed are linked to their parents
+			"INSERT INTO categories VALUES ('category:Auto:Service', 'category:Auto', 'Auto:Service', NULL, 0, 0, NULL, 1);",
+			"INSERT INTO categories VALUES ('category:Utilities', NULL, 'Utilities', NULL, 0, 0, NULL, 1);",
+			"INSERT INTO categories VALUES ('category:Utilities:Gas', 'category:Utilities', 'Utilities:Gas', NULL, 0, 0, NULL, 1);",
+			// the amounts are exact
+			"'category:Auto:Service', NULL, '-1259.95', NULL);",
+			"'2021-01-01', '30', '12', '12', '5.25', '180000.00', '200000.00');",
+		}},
+		{PostgreSQL, []string{
+			"  amount NUMERIC NOT NULL,",
+			"  income BOOLEAN NOT NULL,",
+			"INSERT INTO categories VALUES ('category:Auto:Service', 'category:Auto', 'Auto:Service', NULL, FALSE, FALSE, NULL, TRUE);",
+			"'category:Auto:Service', NULL, -1259.95, NULL);",
+			"'2021-01-01', 30, 12, 12, 5.25, 180000.00, 200000.00);",
+		}},
+	} {
+		var buf bytes.Buffer
+		if err := WriteSQL(&buf, r, SQLOptions{Dialect: tc.dialect}); err != nil {
+			t.Fatalf("%s: %v", tc.dialect, err)
+		}
+		script := buf.String()
+		for _, want := range tc.want {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s: the script doesn't contain %q", tc.dialect, want)
+			}
+		}
+	}
+	if err := WriteSQL(&bytes.Buffer{}, r, SQLOptions{Dialect: "oracle"}); err == nil {
+		t.Errorf("WriteSQL: want an error for an unsupported dialect")
+	}
+}
+
+// TestWriteSQLLoads loads the script into SQLite, if the sqlite3 command is installed.
+func TestWriteSQLLoads(t *testing.T) {
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 isn't installed")
+	}
+	var script bytes.Buffer
+	if err := WriteSQL(&script, readFixture(t, "sql.qif"), SQLOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	script.WriteString("SELECT s.amount, typeof(s.amount) FROM splits s JOIN transactions t ON t.id = s.transaction_id ORDER BY t.line, s.seq;\n")
+	script.WriteString("PRAGMA foreign_key_check;\n")
+	cmd := exec.Command(sqlite, ":memory:")
+	cmd.Stdin = &script
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3: %v\n%s", err, out)
+	}
+	// the foreign key check writes nothing if every reference is to a row
+	got, want := strings.Fields(string(out)), []string{"-1259.95|text", "-30.10|text"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("sqlite3: got %q, want %q", got, want)
+	}
+}
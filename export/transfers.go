@@ -0,0 +1,99 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package export
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+)
+
+// Transfer is a transfer between two accounts, with the half recorded in each account.
+type Transfer struct {
+	ID     string          `json:"id"`
+	Amount decimal.Decimal `json:"amount"`
+	From   TransferHalf    `json:"from"`
+	To     TransferHalf    `json:"to"`
+}
+
+// TransferHalf is the split that records one half of a transfer.
+// The amount is the change to the account.
+type TransferHalf struct {
+	Line            int             `json:"line,omitempty"`
+	TransactionLine int             `json:"transaction_line,omitempty"`
+	Date            string          `json:"date,omitempty"`
+	Account         string          `json:"account,omitempty"`
+	ToAccount       string          `json:"to_account"`
+	Amount          decimal.Decimal `json:"amount"`
+	Payee           string          `json:"payee,omitempty"`
+	Memo            string          `json:"memo,omitempty"`
+}
+
+// Transfers returns the matched transfers.
+func Transfers(transfers *transformer.Transfers) []Transfer {
+	var list []Transfer
+	for _, t := range transfers.Matched {
+		list = append(list, Transfer{ID: t.ID, Amount: t.Amount, From: transferHalfOf(t.From), To: transferHalfOf(t.To)})
+	}
+	return list
+}
+
+// UnmatchedTransfers returns the halves of transfers that weren't matched.
+func UnmatchedTransfers(transfers *transformer.Transfers) []TransferHalf {
+	var list []TransferHalf
+	for _, h := range transfers.Unmatched {
+		list = append(list, transferHalfOf(h))
+	}
+	return list
+}
+
+func transferHalfOf(h transformer.Half) TransferHalf {
+	memo := h.Split.Memo
+	if memo == "" {
+		memo = h.Transaction.Memo
+	}
+	return TransferHalf{
+		Line:            h.Split.Line,
+		TransactionLine: h.Transaction.Line,
+		Date:            h.Transaction.Date,
+		Account:         h.Transaction.Account,
+		ToAccount:       h.Split.Account,
+		Amount:          h.Split.Amount,
+		Payee:           h.Transaction.Payee,
+		Memo:            memo,
+	}
+}
+
+// EncodeTransfers writes the transfers document, with the matched
+// transfers and the halves that weren't matched.
+func EncodeTransfers(w io.Writer, transfers *transformer.Transfers) error {
+	var data struct {
+		Transfers []Transfer     `json:"transfers"`
+		Unmatched []TransferHalf `json:"unmatched"`
+	}
+	data.Transfers = Transfers(transfers)
+	data.Unmatched = UnmatchedTransfers(transfers)
+	return encode(w, data)
+}
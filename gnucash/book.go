@@ -0,0 +1,378 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package gnucash writes the records read from a QIF file as a GnuCash XML book.
+//
+// The account tree has the Quicken accounts under Assets and Liabilities
+// and the category hierarchy under Income and Expenses. Every transaction
+// is written with balanced splits, and investment accounts have a stock
+// or mutual fund account for each security they hold. The securities are
+// written as commodities and the price history as the price database.
+package gnucash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/transformer"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options control the book that is written.
+type Options struct {
+	Currency       string // ISO 4217 code of the book currency, defaults to "USD"
+	Namespace      string // namespace of the securities, defaults to "QIF"
+	DefaultAccount string // name of the account for transactions without one, defaults to "QIF"
+	Compress       bool   // gzip the book, as GnuCash does by default
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Currency == "" {
+		opts.Currency = "USD"
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "QIF"
+	}
+	if opts.DefaultAccount == "" {
+		opts.DefaultAccount = "QIF"
+	}
+	return opts
+}
+
+// The account types of GnuCash.
+const (
+	typeRoot      = "ROOT"
+	typeBank      = "BANK"
+	typeCash      = "CASH"
+	typeCredit    = "CREDIT"
+	typeAsset     = "ASSET"
+	typeLiability = "LIABILITY"
+	typeStock     = "STOCK"
+	typeMutual    = "MUTUAL"
+	typeIncome    = "INCOME"
+	typeExpense   = "EXPENSE"
+	typeEquity    = "EQUITY"
+)
+
+// accountTypes maps the Quicken account types to the GnuCash account types.
+var accountTypes = map[string]string{
+	"Bank":          typeBank,
+	"Cash":          typeCash,
+	"CCard":         typeCredit,
+	"Oth A":         typeAsset,
+	"Oth L":         typeLiability,
+	"Invst":         typeAsset,
+	"Port":          typeAsset,
+	"Mutual":        typeAsset,
+	"401(k)/403(b)": typeAsset,
+}
+
+// topLevel returns the top level account that holds accounts of the type.
+func topLevel(typ string) (name, topType string) {
+	switch typ {
+	case typeCredit, typeLiability:
+		return "Liabilities", typeLiability
+	case typeIncome:
+		return "Income", typeIncome
+	case typeExpense:
+		return "Expenses", typeExpense
+	case typeEquity:
+		return "Equity", typeEquity
+	}
+	return "Assets", typeAsset
+}
+
+type book struct {
+	id           string
+	currency     *commodity
+	namespace    string
+	commodities  []*commodity
+	bySymbol     map[string]*commodity
+	securities   map[string]*security.Record // by name
+	tickers      map[string]string           // the name of the security with the ticker
+	root         *account
+	accounts     []*account // parents before their children
+	byPath       map[string]*account
+	transactions []*transaction
+	prices       []*price
+}
+
+type commodity struct {
+	Space    string
+	ID       string
+	Name     string
+	Fraction int
+}
+
+type account struct {
+	Path        []string
+	ID          string
+	Name        string
+	Type        string
+	Description string
+	Commodity   *commodity
+	Parent      *account
+	Placeholder bool
+}
+
+type transaction struct {
+	ID          string
+	Num         string
+	Date        string // yyyy/mm/dd
+	Description string
+	Notes       string
+	Splits      []*split
+}
+
+type split struct {
+	ID         string
+	Memo       string
+	Reconciled string // n, c or y
+	Value      decimal.Decimal
+	Quantity   decimal.Decimal
+	Account    *account
+}
+
+type price struct {
+	ID        string
+	Commodity *commodity
+	Date      string
+	Value     decimal.Decimal
+}
+
+// guid returns a GUID derived from the parts, so that the same records
+// always produce the same book.
+func guid(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func newBook(r *reader.Reader, opts Options) *book {
+	b := book{
+		id:         guid("book"),
+		namespace:  opts.Namespace,
+		bySymbol:   make(map[string]*commodity),
+		securities: make(map[string]*security.Record),
+		tickers:    make(map[string]string),
+		byPath:     make(map[string]*account),
+	}
+	b.currency = &commodity{Space: "ISO4217", ID: opts.Currency, Fraction: 100}
+	b.root = &account{ID: guid("account", "Root Account"), Name: "Root Account", Type: typeRoot}
+	b.accounts = append(b.accounts, b.root)
+
+	// the securities are the commodities.
+	// investments refer to a security by name and prices by ticker.
+	if r.Securities != nil {
+		for _, record := range r.Securities.Records {
+			b.securities[record.Name] = record
+			if record.Ticker != "" {
+				b.tickers[record.Ticker] = record.Name
+			}
+			b.commodity(record.Name)
+		}
+	}
+
+	// the accounts in the account list, then the categories
+	quickenAccounts := make(map[string]*account)
+	quickenAccount := func(name, quickenType string) *account {
+		if name == "" {
+			name = opts.DefaultAccount
+		}
+		if a, ok := quickenAccounts[name]; ok {
+			return a
+		}
+		typ, ok := accountTypes[quickenType]
+		if !ok {
+			typ = typeAsset
+		}
+		top, _ := topLevel(typ)
+		quickenAccounts[name] = b.account([]string{top, name}, typ, b.currency)
+		return quickenAccounts[name]
+	}
+	if r.Accounts != nil {
+		for _, record := range r.Accounts.Records {
+			quickenAccount(record.Name, record.Type).Description = record.Description
+		}
+	}
+	var tree *category.Tree
+	if r.Categories != nil {
+		tree = r.Categories.Tree()
+		tree.Walk(func(node *category.Node) {
+			a := b.category(node.Path, node.IsIncome)
+			if node.Record != nil {
+				a.Description = node.Record.Description
+			}
+		})
+	}
+	categoryAccount := func(path string, amount decimal.Decimal) *account {
+		// a category that isn't in the list is income if it adds to the account
+		isIncome := amount.Sign() > 0
+		if tree != nil {
+			if node := tree.Find(path); node != nil {
+				isIncome = node.IsIncome
+			}
+		}
+		return b.category(path, isIncome)
+	}
+
+	// A transfer is recorded in both accounts, so one of the halves is dropped.
+	// The half in an investment is always kept.
+	transactions := transformer.NormalizeSplits(r.Transactions)
+	transactions = transformer.CollapseTransfers(transactions, transformer.MatchTransfers(transactions, transformer.MatchOptions{}))
+	transactions = transformer.DropInvestmentTransfers(transactions, investmentTransfers(r.Investments))
+	for i, t := range transactions {
+		id := guid("transaction", "bank", itoa(i))
+		txn := transaction{ID: id, Num: t.RefNo, Date: t.Date, Description: t.Payee, Notes: t.Memo}
+		owner := split{ID: guid(id, "owner"), Reconciled: reconciled(t.ClearedStatus), Account: quickenAccount(t.Account, t.Type)}
+		txn.Splits = append(txn.Splits, &owner)
+		for n, s := range t.Split {
+			owner.Value = owner.Value.Add(s.Amount)
+			other := split{ID: guid(id, itoa(n)), Memo: s.Memo, Reconciled: "n", Value: s.Amount.Neg()}
+			switch {
+			case s.Account != "" && s.Account == t.Account:
+				// Quicken records the opening balance as a transfer from the account to itself
+				other.Account = b.account([]string{"Equity", "Opening Balances"}, typeEquity, b.currency)
+			case s.Account != "":
+				other.Account = quickenAccount(s.Account, "")
+			case s.Category != "":
+				other.Account = categoryAccount(s.Category, s.Amount)
+			default:
+				other.Account = b.account([]string{"Expenses", "Uncategorized"}, typeExpense, b.currency)
+			}
+			txn.Splits = append(txn.Splits, &other)
+		}
+		for _, s := range txn.Splits {
+			s.Quantity = s.Value
+		}
+		b.transactions = append(b.transactions, &txn)
+	}
+
+	for i, record := range r.Investments {
+		if txn := b.investment(record, quickenAccount, categoryAccount); txn != nil {
+			txn.ID = guid("transaction", "investment", itoa(i))
+			for n, s := range txn.Splits {
+				s.ID = guid(txn.ID, itoa(n))
+			}
+			b.transactions = append(b.transactions, txn)
+		}
+	}
+	sort.SliceStable(b.transactions, func(i, j int) bool {
+		return b.transactions[i].Date < b.transactions[j].Date
+	})
+
+	for i, record := range r.Prices {
+		b.prices = append(b.prices, &price{
+			ID:        guid("price", itoa(i)),
+			Commodity: b.commodity(b.securityName(record.Ticker)),
+			Date:      record.Date,
+			Value:     record.Price,
+		})
+	}
+
+	return &b
+}
+
+// account returns the account with the path, creating it and its parents if needed.
+// The parents are placeholders with the type of the top level account.
+func (b *book) account(path []string, typ string, cmdty *commodity) *account {
+	key := strings.Join(path, "\x00")
+	if a, ok := b.byPath[key]; ok {
+		return a
+	}
+	parent := b.root
+	if len(path) > 1 {
+		_, topType := topLevel(typ)
+		parent = b.account(path[:len(path)-1], topType, b.currency)
+	}
+	a := &account{
+		Path:        path,
+		ID:          guid(append([]string{"account"}, path...)...),
+		Name:        path[len(path)-1],
+		Type:        typ,
+		Commodity:   cmdty,
+		Parent:      parent,
+		Placeholder: len(path) == 1,
+	}
+	b.byPath[key] = a
+	b.accounts = append(b.accounts, a)
+	return a
+}
+
+// category returns the income or expense account of a category.
+func (b *book) category(path string, isIncome bool) *account {
+	if isIncome {
+		return b.account(append([]string{"Income"}, strings.Split(path, ":")...), typeIncome, b.currency)
+	}
+	return b.account(append([]string{"Expenses"}, strings.Split(path, ":")...), typeExpense, b.currency)
+}
+
+// commodity returns the commodity of the security with the name or ticker.
+// A security that isn't in the security list is added with the name as its symbol.
+// securityName returns the name of the security with the ticker.
+// If no security has the ticker, the ticker is taken to be the name.
+func (b *book) securityName(ticker string) string {
+	if name, ok := b.tickers[ticker]; ok {
+		return name
+	}
+	return ticker
+}
+
+// commodity returns the commodity of the security with the name, adding it to the book
+// if needed. A security that isn't in the security list has its name as the symbol.
+func (b *book) commodity(name string) *commodity {
+	symbol, fullName := name, name
+	if record, ok := b.securities[name]; ok {
+		fullName = record.Name
+		if record.Ticker != "" {
+			symbol = record.Ticker
+		}
+	}
+	if c, ok := b.bySymbol[symbol]; ok {
+		return c
+	}
+	c := &commodity{Space: b.namespace, ID: symbol, Name: fullName, Fraction: 10000}
+	b.bySymbol[symbol] = c
+	b.commodities = append(b.commodities, c)
+	return c
+}
+
+// reconciled returns the reconciled state of a QIF cleared status.
+func reconciled(status string) string {
+	switch status {
+	case "X", "x", "R", "r":
+		return "y"
+	case "*", "c", "C":
+		return "c"
+	}
+	return "n"
+}
+
+func itoa(i int) string {
+	return strconv.Itoa(i)
+}
@@ -0,0 +1,85 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package gnucash
+
+import (
+	"github.com/mdhender/qif2json/reader"
+	"strings"
+	"testing"
+)
+
+// balances returns the balance of each account in the book by path.
+func balances(b *book) map[string]string {
+	totals := make(map[string]string)
+	sums := make(map[string]*split)
+	for _, txn := range b.transactions {
+		for _, s := range txn.Splits {
+			path := strings.Join(s.Account.Path, ":")
+			if sums[path] == nil {
+				sums[path] = &split{}
+			}
+			sums[path].Value = sums[path].Value.Add(s.Value)
+		}
+	}
+	for path, s := range sums {
+		totals[path] = s.Value.String()
+	}
+	return totals
+}
+
+func TestTransfersAreWrittenOnce(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{"single splits",
+			"!Type:Bank\nD1/1'21\nT-100.00\nL[Savings]\n^\n" +
+				"!Account\nNSavings\nTBank\n^\n!Type:Bank\nD1/1'21\nT100.00\nL[Checking]\n^\n",
+			map[string]string{"Assets:Checking": "-100.00", "Assets:Savings": "100.00"}},
+		{"split to split",
+			"!Type:Bank\nD1/1'21\nT-250.00\nSUtilities\n$-150.00\nS[Savings]\n$-100.00\n^\n" +
+				"!Account\nNSavings\nTBank\n^\n!Type:Bank\nD1/1'21\nT250.00\nSInterest\n$150.00\nS[Checking]\n$100.00\n^\n",
+			map[string]string{"Assets:Checking": "-250.00", "Assets:Savings": "250.00", "Expenses:Utilities": "150.00", "Income:Interest": "-150.00"}},
+		{"investment transfer",
+			"!Type:Bank\nD1/5'21\nT-1000.00\nL[Brokerage]\n^\n" +
+				"!Account\nNBrokerage\nTInvst\n^\n!Type:Invst\nD1/5'21\nNXIn\nT1000.00\nL[Checking]\n^\n",
+			map[string]string{"Assets:Checking": "-1000.00", "Assets:Brokerage": "1000.00"}},
+	} {
+		// the first account is in the account list, so the records after it are in Checking
+		input := "!Option:AutoSwitch\n!Account\nNChecking\nTBank\n^\nNSavings\nTBank\n^\nNBrokerage\nTInvst\n^\n!Clear:AutoSwitch\n" +
+			"!Account\nNChecking\nTBank\n^\n" + tc.input
+		r, err := reader.ReadFrom(strings.NewReader(input), reader.Options{})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got := balances(newBook(r, Options{}.withDefaults()))
+		for path, want := range tc.want {
+			if got[path] != want {
+				t.Errorf("%s: %s: got %q, want %q", tc.name, path, got[path], want)
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package gnucash
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Write writes the records as a GnuCash XML book, compressed if the options ask for it.
+// The GUIDs are derived from the records, so the same records always produce the same book.
+func Write(w io.Writer, r *reader.Reader, opts Options) error {
+	opts = opts.withDefaults()
+	if opts.Compress {
+		zw := gzip.NewWriter(w)
+		if err := write(zw, newBook(r, opts)); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+	return write(w, newBook(r, opts))
+}
+
+// namespaces are the XML namespaces of the book.
+var namespaces = []string{"gnc", "act", "book", "cd", "cmdty", "price", "slot", "split", "trn", "ts"}
+
+func write(w io.Writer, b *book) error {
+	gw := writer{w: bufio.NewWriter(w)}
+
+	gw.line(0, `<?xml version="1.0" encoding="utf-8" ?>`)
+	gw.line(0, "<gnc-v2")
+	for _, ns := range namespaces {
+		gw.line(1, `xmlns:`+ns+`="http://www.gnucash.org/XML/`+ns+`"`)
+	}
+	gw.line(0, ">")
+	gw.line(0, `<gnc:count-data cd:type="book">1</gnc:count-data>`)
+	gw.line(0, `<gnc:book version="2.0.0">`)
+	gw.line(1, `<book:id type="guid">`+b.id+`</book:id>`)
+	gw.count("commodity", len(b.commodities))
+	gw.count("account", len(b.accounts))
+	gw.count("transaction", len(b.transactions))
+	if len(b.prices) != 0 {
+		gw.count("price", len(b.prices))
+	}
+
+	for _, c := range append([]*commodity{b.currency}, b.commodities...) {
+		gw.line(1, `<gnc:commodity version="2.0.0">`)
+		gw.elem(2, "cmdty:space", c.Space)
+		gw.elem(2, "cmdty:id", c.ID)
+		if c != b.currency {
+			gw.elem(2, "cmdty:name", c.Name)
+			gw.elem(2, "cmdty:fraction", strconv.Itoa(c.Fraction))
+		}
+		gw.line(1, `</gnc:commodity>`)
+	}
+
+	if len(b.prices) != 0 {
+		gw.line(1, `<gnc:pricedb version="1">`)
+		for _, p := range b.prices {
+			gw.line(2, `<price>`)
+			gw.line(3, `<price:id type="guid">`+p.ID+`</price:id>`)
+			gw.commodityRef(3, "price:commodity", p.Commodity)
+			gw.commodityRef(3, "price:currency", b.currency)
+			gw.date(3, "price:time", p.Date)
+			gw.elem(3, "price:source", "user:price")
+			gw.elem(3, "price:value", rational(p.Value))
+			gw.line(2, `</price>`)
+		}
+		gw.line(1, `</gnc:pricedb>`)
+	}
+
+	for _, a := range b.accounts {
+		gw.line(1, `<gnc:account version="2.0.0">`)
+		gw.elem(2, "act:name", a.Name)
+		gw.line(2, `<act:id type="guid">`+a.ID+`</act:id>`)
+		gw.elem(2, "act:type", a.Type)
+		if a.Commodity != nil {
+			gw.commodityRef(2, "act:commodity", a.Commodity)
+			gw.elem(2, "act:commodity-scu", strconv.Itoa(a.Commodity.Fraction))
+		}
+		if a.Description != "" {
+			gw.elem(2, "act:description", a.Description)
+		}
+		if a.Placeholder {
+			gw.line(2, `<act:slots>`)
+			gw.slot(3, "placeholder", "true")
+			gw.line(2, `</act:slots>`)
+		}
+		if a.Parent != nil {
+			gw.line(2, `<act:parent type="guid">`+a.Parent.ID+`</act:parent>`)
+		}
+		gw.line(1, `</gnc:account>`)
+	}
+
+	for _, t := range b.transactions {
+		gw.line(1, `<gnc:transaction version="2.0.0">`)
+		gw.line(2, `<trn:id type="guid">`+t.ID+`</trn:id>`)
+		gw.commodityRef(2, "trn:currency", b.currency)
+		if t.Num != "" {
+			gw.elem(2, "trn:num", t.Num)
+		}
+		gw.date(2, "trn:date-posted", t.Date)
+		gw.date(2, "trn:date-entered", t.Date)
+		gw.elem(2, "trn:description", t.Description)
+		if t.Notes != "" {
+			gw.line(2, `<trn:slots>`)
+			gw.slot(3, "notes", t.Notes)
+			gw.line(2, `</trn:slots>`)
+		}
+		gw.line(2, `<trn:splits>`)
+		for _, s := range t.Splits {
+			gw.line(3, `<trn:split>`)
+			gw.line(4, `<split:id type="guid">`+s.ID+`</split:id>`)
+			if s.Memo != "" {
+				gw.elem(4, "split:memo", s.Memo)
+			}
+			gw.elem(4, "split:reconciled-state", s.Reconciled)
+			gw.elem(4, "split:value", rational(s.Value.Round(2)))
+			gw.elem(4, "split:quantity", rational(s.Quantity))
+			gw.line(4, `<split:account type="guid">`+s.Account.ID+`</split:account>`)
+			gw.line(3, `</trn:split>`)
+		}
+		gw.line(2, `</trn:splits>`)
+		gw.line(1, `</gnc:transaction>`)
+	}
+
+	gw.line(0, `</gnc:book>`)
+	gw.line(0, `</gnc-v2>`)
+
+	if gw.err != nil {
+		return gw.err
+	}
+	return gw.w.Flush()
+}
+
+// writer writes the elements of the book.
+// The first error is saved and later writes are ignored.
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (gw *writer) line(depth int, text string) {
+	if gw.err == nil {
+		_, gw.err = gw.w.WriteString(strings.Repeat("  ", depth) + text + "\n")
+	}
+}
+
+// elem writes an element with the text escaped.
+func (gw *writer) elem(depth int, name, text string) {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(text))
+	gw.line(depth, "<"+name+">"+sb.String()+"</"+name+">")
+}
+
+func (gw *writer) count(typ string, n int) {
+	gw.line(1, `<gnc:count-data cd:type="`+typ+`">`+strconv.Itoa(n)+`</gnc:count-data>`)
+}
+
+func (gw *writer) commodityRef(depth int, name string, c *commodity) {
+	gw.line(depth, "<"+name+">")
+	gw.elem(depth+1, "cmdty:space", c.Space)
+	gw.elem(depth+1, "cmdty:id", c.ID)
+	gw.line(depth, "</"+name+">")
+}
+
+// date writes a yyyy/mm/dd date at the time GnuCash uses for dates without one.
+func (gw *writer) date(depth int, name, date string) {
+	gw.line(depth, "<"+name+">")
+	gw.elem(depth+1, "ts:date", strings.ReplaceAll(date, "/", "-")+" 10:59:00 +0000")
+	gw.line(depth, "</"+name+">")
+}
+
+func (gw *writer) slot(depth int, key, value string) {
+	gw.line(depth, `<slot>`)
+	gw.elem(depth+1, "slot:key", key)
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(value))
+	gw.line(depth+1, `<slot:value type="string">`+sb.String()+`</slot:value>`)
+	gw.line(depth, `</slot>`)
+}
+
+// rational returns the number as the numerator/denominator that GnuCash stores.
+// For example, "-12.50" is "-1250/100".
+func rational(d decimal.Decimal) string {
+	text := d.String()
+	denominator := "1"
+	if i := strings.IndexByte(text, '.'); i != -1 {
+		denominator += strings.Repeat("0", len(text)-i-1)
+		text = text[:i] + text[i+1:]
+	}
+	numerator, _ := strconv.ParseInt(text, 10, 64)
+	return strconv.FormatInt(numerator, 10) + "/" + denominator
+}
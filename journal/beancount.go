@@ -0,0 +1,157 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// writeBeancount writes the journal in beancount syntax.
+// Beancount is strict about names, so account names and commodities are
+// rewritten to use only the characters it accepts. The QIF cleared status,
+// check number, memos and classes are kept as metadata.
+func writeBeancount(w io.Writer, j *journal) error {
+	bw := bufio.NewWriter(w)
+	date := func(date string) string {
+		return strings.ReplaceAll(date, "/", "-")
+	}
+
+	fmt.Fprintf(bw, "option \"operating_currency\" %s\n\n", strconv.Quote(beancountCommodity(j.currency)))
+
+	width := 0
+	for _, a := range j.accounts {
+		if n := len([]rune(beancountAccount(a.Name))); n > width {
+			width = n
+		}
+	}
+
+	for _, a := range j.accounts {
+		fmt.Fprintf(bw, "%s open %s\n", date(a.Opened), beancountAccount(a.Name))
+		if a.Note != "" {
+			fmt.Fprintf(bw, "  description: %s\n", strconv.Quote(a.Note))
+		}
+	}
+	if len(j.commodities) != 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, c := range j.commodities {
+		fmt.Fprintf(bw, "%s commodity %s\n", date(j.first), beancountCommodity(c.Symbol))
+		if c.Name != "" {
+			fmt.Fprintf(bw, "  name: %s\n", strconv.Quote(c.Name))
+		}
+	}
+	if len(j.prices) != 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, p := range j.prices {
+		fmt.Fprintf(bw, "%s price %s %s %s\n", date(p.Date), beancountCommodity(p.Symbol), p.Price, beancountCommodity(j.currency))
+	}
+
+	for _, e := range j.entries {
+		fmt.Fprintf(bw, "\n%s *", date(e.Date))
+		if e.Payee != "" {
+			fmt.Fprintf(bw, " %s", strconv.Quote(e.Payee))
+		}
+		fmt.Fprintf(bw, " %s\n", strconv.Quote(e.Memo))
+		if e.Code != "" {
+			fmt.Fprintf(bw, "  check: %s\n", strconv.Quote(e.Code))
+		}
+		if e.Cleared != "" {
+			fmt.Fprintf(bw, "  cleared: %s\n", strconv.Quote(e.Cleared))
+		}
+		for _, p := range e.Postings {
+			if p.Commodity != "" {
+				fmt.Fprintf(bw, "  %-*s  %s %s @@ %s %s\n", width, beancountAccount(p.Account), p.Quantity, beancountCommodity(p.Commodity), p.Amount.Abs(), beancountCommodity(j.currency))
+			} else {
+				fmt.Fprintf(bw, "  %-*s  %s %s\n", width, beancountAccount(p.Account), p.Amount, beancountCommodity(j.currency))
+			}
+			if p.Memo != "" {
+				fmt.Fprintf(bw, "    memo: %s\n", strconv.Quote(p.Memo))
+			}
+			if p.Class != "" {
+				fmt.Fprintf(bw, "    class: %s\n", strconv.Quote(p.Class))
+			}
+		}
+	}
+
+	return bw.Flush()
+}
+
+// beancountAccount returns the account name with each component starting
+// with a capital letter or digit and containing only letters, digits and dashes.
+func beancountAccount(name string) string {
+	components := strings.Split(name, ":")
+	for i, component := range components {
+		var sb strings.Builder
+		dash := false
+		for _, ch := range component {
+			if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+				if dash && sb.Len() != 0 {
+					sb.WriteByte('-')
+				}
+				dash = false
+				if sb.Len() == 0 {
+					ch = unicode.ToUpper(ch)
+				}
+				sb.WriteRune(ch)
+			} else {
+				dash = true
+			}
+		}
+		component = sb.String()
+		if component == "" || !(unicode.IsUpper([]rune(component)[0]) || unicode.IsDigit([]rune(component)[0])) {
+			component = "X" + component
+		}
+		components[i] = component
+	}
+	return strings.Join(components, ":")
+}
+
+// beancountCommodity returns the commodity in capital letters, starting with
+// a letter, containing only letters, digits and the characters ' . _ -, and
+// ending with a letter or digit.
+func beancountCommodity(symbol string) string {
+	symbol = strings.Map(func(ch rune) rune {
+		switch {
+		case 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9', strings.ContainsRune("'._-", ch):
+			return ch
+		case 'a' <= ch && ch <= 'z':
+			return unicode.ToUpper(ch)
+		}
+		return '-'
+	}, symbol)
+	if symbol == "" || symbol[0] < 'A' || symbol[0] > 'Z' {
+		symbol = "X" + symbol
+	}
+	if len(symbol) > 24 {
+		symbol = symbol[:24]
+	}
+	return strings.TrimRight(symbol, "'._-")
+}
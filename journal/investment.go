@@ -0,0 +1,193 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package journal
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/transformer"
+	"strings"
+)
+
+// incomeAccounts are the income accounts of the income actions without a category.
+var incomeAccounts = map[investment.Action]string{
+	investment.Div: "Dividends", investment.DivX: "Dividends", investment.ReinvDiv: "Dividends",
+	investment.IntInc: "Interest", investment.IntIncX: "Interest", investment.ReinvInt: "Interest",
+	investment.CGLong: "Capital Gains:Long Term", investment.CGLongX: "Capital Gains:Long Term", investment.ReinvLg: "Capital Gains:Long Term",
+	investment.CGMid: "Capital Gains:Mid Term", investment.CGMidX: "Capital Gains:Mid Term", investment.ReinvMd: "Capital Gains:Mid Term",
+	investment.CGShort: "Capital Gains:Short Term", investment.CGShortX: "Capital Gains:Short Term", investment.ReinvSh: "Capital Gains:Short Term",
+	investment.MiscInc: "Miscellaneous", investment.MiscIncX: "Miscellaneous", investment.ReinvCash: "Miscellaneous",
+}
+
+// amountOf returns the total amount of an investment transaction.
+// If the record doesn't have one, it is calculated from the shares and price.
+func amountOf(record *investment.Record) decimal.Decimal {
+	amount := record.Amount
+	if amount.IsZero() {
+		amount = record.AmountUCode
+	}
+	if amount.IsZero() && !record.Shares.IsZero() {
+		amount = record.Shares.Mul(record.Price).Round(2)
+		switch record.Action {
+		case investment.Buy, investment.BuyX, investment.CvrShrt:
+			amount = amount.Add(record.Commission)
+		case investment.Sell, investment.SellX, investment.ShtSell:
+			amount = amount.Sub(record.Commission)
+		}
+	}
+	if record.Action == investment.Cash {
+		return amount
+	}
+	return amount.Abs()
+}
+
+// investmentTransfer returns the cash moved into the investment account from
+// the transfer account by an action that transfers cash.
+func investmentTransfer(record *investment.Record) (decimal.Decimal, bool) {
+	if record.TransferAccount == "" {
+		return decimal.Decimal{}, false
+	}
+	switch record.Action {
+	case investment.BuyX, investment.MiscExpX, investment.MargIntX, investment.XIn, investment.ContribX, investment.Cash:
+		return amountOf(record), true
+	case investment.SellX, investment.DivX, investment.IntIncX, investment.CGLongX, investment.CGMidX, investment.CGShortX,
+		investment.RtrnCapX, investment.MiscIncX, investment.XOut, investment.WithdrwX:
+		return amountOf(record).Neg(), true
+	}
+	return decimal.Decimal{}, false
+}
+
+// investmentTransfers returns the cash moved by the investment actions that transfer cash.
+func investmentTransfers(records []*investment.Record) []transformer.InvestmentTransfer {
+	var transfers []transformer.InvestmentTransfer
+	for _, record := range records {
+		if amount, ok := investmentTransfer(record); ok {
+			transfers = append(transfers, transformer.InvestmentTransfer{Account: record.Account, TransferAccount: record.TransferAccount, Date: record.Date, Amount: amount})
+		}
+	}
+	return transfers
+}
+
+// investmentEntry returns the entry of an investment action,
+// or nil if the action doesn't change the balances.
+//
+// Cash is held in the investment account and the shares in an account
+// for the security under it. The shares are posted in the commodity of
+// the security at the total cost of the action. When an action transfers
+// cash, the cash is posted to the transfer account instead of the
+// investment account. Stock splits, reminders and the employee stock
+// option actions are not written.
+func investmentEntry(record *investment.Record, opts Options, accountName func(name, quickenType string) string, declare func(name, typ string) string, categoryAccount func(path string, amount decimal.Decimal) string, symbol func(security string) string) *entry {
+	amount := amountOf(record)
+	owner := accountName(record.Account, "Invst")
+	e := entry{Line: record.Line, Date: record.Date, Cleared: record.ClearedStatus, Payee: record.Payee, Memo: record.Memo}
+	if e.Payee == "" {
+		e.Payee = strings.TrimSpace(string(record.Action) + " " + record.Security)
+	}
+	add := func(account string, amount decimal.Decimal) *posting {
+		p := &posting{Account: account, Amount: amount}
+		e.Postings = append(e.Postings, p)
+		return p
+	}
+	cash := owner
+	if _, ok := investmentTransfer(record); ok {
+		cash = accountName(record.TransferAccount, "")
+	}
+	stock := func(value, shares decimal.Decimal) {
+		p := add(declare(owner+":"+record.Security, asset), value)
+		if !shares.IsZero() {
+			// a return of capital changes the cost of the shares, not the number held
+			p.Quantity, p.Commodity = shares, symbol(record.Security)
+		}
+	}
+	commission := func() {
+		if !record.Commission.IsZero() {
+			add(declare(expense+":Commissions", expense), record.Commission)
+		}
+	}
+	category := func(value decimal.Decimal, isIncome bool, fallback string) {
+		var p *posting
+		if record.Category != "" {
+			p = add(categoryAccount(record.Category, value.Neg()), value)
+		} else if isIncome {
+			p = add(declare(income+":"+fallback, income), value)
+		} else {
+			p = add(declare(expense+":"+fallback, expense), value)
+		}
+		p.Class = record.Class
+	}
+
+	switch record.Action {
+	case investment.Buy, investment.BuyX, investment.CvrShrt:
+		stock(amount.Sub(record.Commission), record.Shares.Abs())
+		commission()
+		add(cash, amount.Neg())
+	case investment.Sell, investment.SellX, investment.ShtSell:
+		stock(amount.Add(record.Commission).Neg(), record.Shares.Abs().Neg())
+		commission()
+		add(cash, amount)
+	case investment.Div, investment.DivX, investment.IntInc, investment.IntIncX,
+		investment.CGLong, investment.CGLongX, investment.CGMid, investment.CGMidX,
+		investment.CGShort, investment.CGShortX, investment.MiscInc, investment.MiscIncX:
+		add(cash, amount)
+		category(amount.Neg(), true, incomeAccounts[record.Action])
+	case investment.ReinvDiv, investment.ReinvInt, investment.ReinvLg, investment.ReinvMd, investment.ReinvSh, investment.ReinvCash:
+		stock(amount, record.Shares.Abs())
+		category(amount.Neg(), true, incomeAccounts[record.Action])
+	case investment.RtrnCap, investment.RtrnCapX:
+		add(cash, amount)
+		stock(amount.Neg(), decimal.Decimal{})
+	case investment.MiscExp, investment.MiscExpX:
+		add(cash, amount.Neg())
+		category(amount, false, "Miscellaneous")
+	case investment.MargInt, investment.MargIntX:
+		add(cash, amount.Neg())
+		category(amount, false, "Margin Interest")
+	case investment.ShrsIn, investment.ShrsInX:
+		stock(amount, record.Shares.Abs())
+		add(declare(opts.OpeningBalance, equity), amount.Neg())
+	case investment.ShrsOut, investment.ShrsOutX:
+		stock(amount.Neg(), record.Shares.Abs().Neg())
+		add(declare(opts.OpeningBalance, equity), amount)
+	case investment.XIn, investment.ContribX:
+		add(owner, amount)
+		add(cash, amount.Neg())
+	case investment.XOut, investment.WithdrwX:
+		add(owner, amount.Neg())
+		add(cash, amount)
+	case investment.Cash:
+		add(owner, amount)
+		if cash != owner {
+			add(cash, amount.Neg())
+		} else if record.Category != "" {
+			add(categoryAccount(record.Category, amount), amount.Neg()).Class = record.Class
+		} else {
+			add(declare(opts.Uncategorized, expense), amount.Neg())
+		}
+	default:
+		return nil
+	}
+	return &e
+}
@@ -0,0 +1,333 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package journal writes the transactions read from a QIF file as a
+// plain-text accounting journal for ledger, hledger or beancount.
+//
+// Each transaction becomes a balanced entry with a posting to the account
+// it was recorded in and a posting for each split. Categories are written
+// as accounts under Income or Expenses, and transfers as postings to the
+// asset or liability account on the other side. A transfer that was
+// recorded in both accounts is only written once. Investment actions are
+// written with the shares posted in the commodity of the security.
+package journal
+
+import (
+	"fmt"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/transformer"
+	"io"
+	"sort"
+)
+
+// Format is the syntax of the journal.
+type Format string
+
+const (
+	Ledger    Format = "ledger"
+	HLedger   Format = "hledger"
+	Beancount Format = "beancount"
+)
+
+// Options control the journal that is written.
+type Options struct {
+	Currency       string // commodity of the amounts, defaults to "USD"
+	DefaultAccount string // name of the account for transactions without one, defaults to "QIF"
+	Uncategorized  string // account for splits without a category, defaults to "Expenses:Uncategorized"
+	OpeningBalance string // account for opening balances, defaults to "Equity:Opening Balances"
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Currency == "" {
+		opts.Currency = "USD"
+	}
+	if opts.DefaultAccount == "" {
+		opts.DefaultAccount = "QIF"
+	}
+	if opts.Uncategorized == "" {
+		opts.Uncategorized = "Expenses:Uncategorized"
+	}
+	if opts.OpeningBalance == "" {
+		opts.OpeningBalance = "Equity:Opening Balances"
+	}
+	return opts
+}
+
+// FormatError reports a journal format that isn't supported.
+type FormatError struct {
+	Format string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("unknown journal format %q", e.Format)
+}
+
+// Write writes the accounts, transactions and prices of the reader as a journal.
+func Write(w io.Writer, r *reader.Reader, format Format, opts Options) error {
+	switch format {
+	case Ledger, HLedger:
+		return writeLedger(w, newJournal(r, opts.withDefaults()), format)
+	case Beancount:
+		return writeBeancount(w, newJournal(r, opts.withDefaults()))
+	}
+	return &FormatError{Format: string(format)}
+}
+
+// The top level accounts.
+const (
+	asset     = "Assets"
+	liability = "Liabilities"
+	equity    = "Equity"
+	income    = "Income"
+	expense   = "Expenses"
+)
+
+// journal is the entries of the journal before they are formatted.
+// Account names are colon separated paths that start with a top level account.
+type journal struct {
+	currency    string
+	first       string // the date of the first entry or price
+	accounts    []*accountDecl
+	commodities []*commodity
+	entries     []*entry
+	prices      []*price
+}
+
+type accountDecl struct {
+	Name   string
+	Type   string // the top level account
+	Note   string
+	Opened string // date of the first entry that uses the account
+}
+
+type commodity struct {
+	Symbol string
+	Name   string
+}
+
+type entry struct {
+	Line     int
+	Date     string // yyyy/mm/dd
+	Cleared  string // the QIF cleared status
+	Code     string // the check or reference number
+	Payee    string
+	Memo     string
+	Postings []*posting
+}
+
+type posting struct {
+	Account   string
+	Amount    decimal.Decimal // in the currency of the journal
+	Quantity  decimal.Decimal // the number of shares, if Commodity is set
+	Commodity string          // the symbol of the security, for a posting of shares
+	Class     string
+	Memo      string
+}
+
+type price struct {
+	Date   string
+	Symbol string
+	Price  decimal.Decimal
+}
+
+// accountTypes maps the Quicken account types to the top level accounts.
+var accountTypes = map[string]string{
+	"Bank":          asset,
+	"Cash":          asset,
+	"Oth A":         asset,
+	"Invst":         asset,
+	"Port":          asset,
+	"Mutual":        asset,
+	"401(k)/403(b)": asset,
+	"CCard":         liability,
+	"Oth L":         liability,
+}
+
+func newJournal(r *reader.Reader, opts Options) *journal {
+	j := journal{currency: opts.Currency}
+	declared := make(map[string]*accountDecl)
+	declare := func(name, typ string) string {
+		if _, ok := declared[name]; !ok {
+			declared[name] = &accountDecl{Name: name, Type: typ}
+			j.accounts = append(j.accounts, declared[name])
+		}
+		return name
+	}
+
+	// the asset and liability accounts, from the account list if they are in it
+	accountNames := make(map[string]string)
+	accountName := func(name, quickenType string) string {
+		if name == "" {
+			name = opts.DefaultAccount
+		}
+		if path, ok := accountNames[name]; ok {
+			return path
+		}
+		typ, ok := accountTypes[quickenType]
+		if !ok {
+			typ = asset
+		}
+		accountNames[name] = declare(typ+":"+name, typ)
+		return accountNames[name]
+	}
+	if r.Accounts != nil {
+		for _, record := range r.Accounts.Records {
+			declared[accountName(record.Name, record.Type)].Note = record.Description
+		}
+	}
+
+	// the income and expense accounts from the category list
+	var tree *category.Tree
+	if r.Categories != nil {
+		tree = r.Categories.Tree()
+		tree.Walk(func(node *category.Node) {
+			name := declare(categoryName(node.Path, node.IsIncome), typeOf(node.IsIncome))
+			if node.Record != nil {
+				declared[name].Note = node.Record.Description
+			}
+		})
+	}
+	categoryAccount := func(path string, amount decimal.Decimal) string {
+		// a category that isn't in the list is income if it adds to the account
+		isIncome := amount.Sign() > 0
+		if tree != nil {
+			if node := tree.Find(path); node != nil {
+				isIncome = node.IsIncome
+			}
+		}
+		return declare(categoryName(path, isIncome), typeOf(isIncome))
+	}
+
+	// A transfer is recorded in both accounts, so one of the halves is dropped.
+	// The half in an investment is always kept.
+	transactions := transformer.NormalizeSplits(r.Transactions)
+	transactions = transformer.CollapseTransfers(transactions, transformer.MatchTransfers(transactions, transformer.MatchOptions{}))
+	transactions = transformer.DropInvestmentTransfers(transactions, investmentTransfers(r.Investments))
+	for _, t := range transactions {
+		e := entry{Line: t.Line, Date: t.Date, Cleared: t.ClearedStatus, Code: t.RefNo, Payee: t.Payee, Memo: t.Memo}
+		owner := posting{Account: accountName(t.Account, t.Type)}
+		e.Postings = append(e.Postings, &owner)
+		for _, split := range t.Split {
+			owner.Amount = owner.Amount.Add(split.Amount)
+			p := posting{Amount: split.Amount.Neg(), Class: split.Class, Memo: split.Memo}
+			switch {
+			case split.Account != "" && split.Account == t.Account:
+				// Quicken records the opening balance as a transfer from the account to itself
+				p.Account = declare(opts.OpeningBalance, equity)
+			case split.Account != "":
+				p.Account = accountName(split.Account, "")
+			case split.Category != "":
+				p.Account = categoryAccount(split.Category, split.Amount)
+			default:
+				p.Account = declare(opts.Uncategorized, expense)
+			}
+			e.Postings = append(e.Postings, &p)
+		}
+		j.entries = append(j.entries, &e)
+	}
+
+	// the commodities are the securities and the tickers in the price history.
+	// investments refer to a security by name, so a security that isn't in
+	// the list is a commodity with its name as the symbol.
+	symbols := make(map[string]bool)
+	securitySymbols := make(map[string]string)
+	addCommodity := func(symbol, name string) {
+		if !symbols[symbol] {
+			symbols[symbol] = true
+			j.commodities = append(j.commodities, &commodity{Symbol: symbol, Name: name})
+		}
+	}
+	if r.Securities != nil {
+		for _, record := range r.Securities.Records {
+			symbol := record.Ticker
+			if symbol == "" {
+				symbol = record.Name
+			}
+			securitySymbols[record.Name] = symbol
+			addCommodity(symbol, record.Name)
+		}
+	}
+	symbol := func(security string) string {
+		if symbol, ok := securitySymbols[security]; ok {
+			return symbol
+		}
+		addCommodity(security, "")
+		return security
+	}
+
+	for _, record := range r.Investments {
+		if e := investmentEntry(record, opts, accountName, declare, categoryAccount, symbol); e != nil {
+			j.entries = append(j.entries, e)
+		}
+	}
+	sort.SliceStable(j.entries, func(a, b int) bool {
+		return j.entries[a].Date < j.entries[b].Date
+	})
+
+	// accounts are opened on the date of their first entry
+	for _, e := range j.entries {
+		for _, p := range e.Postings {
+			if a := declared[p.Account]; a.Opened == "" {
+				a.Opened = e.Date
+			}
+		}
+	}
+
+	for _, record := range r.Prices {
+		addCommodity(record.Ticker, "")
+		j.prices = append(j.prices, &price{Date: record.Date, Symbol: record.Ticker, Price: record.Price})
+	}
+	sort.SliceStable(j.prices, func(a, b int) bool {
+		return j.prices[a].Date < j.prices[b].Date
+	})
+
+	j.first = "1970/01/01"
+	if len(j.entries) != 0 {
+		j.first = j.entries[0].Date
+	}
+	if len(j.prices) != 0 && (len(j.entries) == 0 || j.prices[0].Date < j.first) {
+		j.first = j.prices[0].Date
+	}
+	for _, a := range j.accounts {
+		if a.Opened == "" {
+			a.Opened = j.first
+		}
+	}
+
+	return &j
+}
+
+// categoryName returns the account of a category.
+func categoryName(path string, isIncome bool) string {
+	return typeOf(isIncome) + ":" + path
+}
+
+func typeOf(isIncome bool) string {
+	if isIncome {
+		return income
+	}
+	return expense
+}
@@ -0,0 +1,155 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// hledgerTypes are the hledger account type codes of the top level accounts.
+var hledgerTypes = map[string]string{
+	asset:     "A",
+	liability: "L",
+	equity:    "E",
+	income:    "R",
+	expense:   "X",
+}
+
+// writeLedger writes the journal in ledger or hledger syntax.
+// The two differ in the date format and in how accounts and commodities are annotated.
+//
+// Reconciled transactions are marked cleared (*) and transactions that are
+// cleared in Quicken but not yet reconciled are marked pending (!).
+func writeLedger(w io.Writer, j *journal, format Format) error {
+	bw := bufio.NewWriter(w)
+	date := func(date string) string {
+		if format == HLedger {
+			return strings.ReplaceAll(date, "/", "-")
+		}
+		return date
+	}
+
+	width := 0
+	for _, a := range j.accounts {
+		if n := len([]rune(ledgerAccount(a.Name))); n > width {
+			width = n
+		}
+	}
+
+	for _, a := range j.accounts {
+		switch {
+		case format == HLedger:
+			fmt.Fprintf(bw, "account %s  ; type:%s\n", ledgerAccount(a.Name), hledgerTypes[a.Type])
+		case a.Note != "":
+			fmt.Fprintf(bw, "account %s\n    note %s\n", ledgerAccount(a.Name), oneLine(a.Note))
+		default:
+			fmt.Fprintf(bw, "account %s\n", ledgerAccount(a.Name))
+		}
+	}
+	if len(j.commodities) != 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, c := range j.commodities {
+		switch {
+		case c.Name == "":
+			fmt.Fprintf(bw, "commodity %s\n", ledgerCommodity(c.Symbol))
+		case format == HLedger:
+			fmt.Fprintf(bw, "commodity %s  ; %s\n", ledgerCommodity(c.Symbol), oneLine(c.Name))
+		default:
+			fmt.Fprintf(bw, "commodity %s\n    note %s\n", ledgerCommodity(c.Symbol), oneLine(c.Name))
+		}
+	}
+	if len(j.prices) != 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, p := range j.prices {
+		fmt.Fprintf(bw, "P %s %s %s %s\n", date(p.Date), ledgerCommodity(p.Symbol), p.Price, ledgerCommodity(j.currency))
+	}
+
+	for _, e := range j.entries {
+		fmt.Fprintf(bw, "\n%s", date(e.Date))
+		switch e.Cleared {
+		case "X", "x", "R", "r":
+			fmt.Fprint(bw, " *")
+		case "*", "c", "C":
+			fmt.Fprint(bw, " !")
+		}
+		if e.Code != "" {
+			fmt.Fprintf(bw, " (%s)", strings.Trim(oneLine(e.Code), "()"))
+		}
+		if e.Payee != "" {
+			fmt.Fprintf(bw, " %s", oneLine(e.Payee))
+		}
+		if e.Memo != "" {
+			fmt.Fprintf(bw, "  ; %s", oneLine(e.Memo))
+		}
+		fmt.Fprintln(bw)
+		for _, p := range e.Postings {
+			if p.Commodity != "" {
+				fmt.Fprintf(bw, "    %-*s  %s %s @@ %s %s", width, ledgerAccount(p.Account), p.Quantity, ledgerCommodity(p.Commodity), p.Amount.Abs(), ledgerCommodity(j.currency))
+			} else {
+				fmt.Fprintf(bw, "    %-*s  %s %s", width, ledgerAccount(p.Account), p.Amount, ledgerCommodity(j.currency))
+			}
+			var notes []string
+			if p.Memo != "" {
+				notes = append(notes, oneLine(p.Memo))
+			}
+			if p.Class != "" {
+				notes = append(notes, "class: "+oneLine(p.Class))
+			}
+			if len(notes) != 0 {
+				fmt.Fprintf(bw, "  ; %s", strings.Join(notes, ", "))
+			}
+			fmt.Fprintln(bw)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ledgerAccount returns the account name with the runs of spaces that
+// would separate it from the amount replaced by a single space.
+func ledgerAccount(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// ledgerCommodity returns the commodity, quoted if it contains anything but letters.
+func ledgerCommodity(symbol string) string {
+	for _, ch := range symbol {
+		if !unicode.IsLetter(ch) {
+			return `"` + strings.ReplaceAll(symbol, `"`, "") + `"`
+		}
+	}
+	return symbol
+}
+
+// oneLine returns the text with the line breaks replaced by spaces.
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,290 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package ofx
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"strings"
+)
+
+// securities finds the securities named by investment transactions
+// and collects the ones used for the security list.
+type securities struct {
+	byName   map[string]*security.Record
+	byTicker map[string]*security.Record
+	used     map[string]*security.Record
+}
+
+func newSecurities(section *security.Section) *securities {
+	s := securities{
+		byName:   make(map[string]*security.Record),
+		byTicker: make(map[string]*security.Record),
+		used:     make(map[string]*security.Record),
+	}
+	if section != nil {
+		for _, record := range section.Records {
+			s.byName[record.Name] = record
+			if record.Ticker != "" {
+				s.byTicker[record.Ticker] = record
+			}
+		}
+	}
+	return &s
+}
+
+// find returns the security with the name or ticker.
+// A security that isn't in the list is created from the name.
+func (s *securities) find(name string) *security.Record {
+	record, ok := s.byName[name]
+	if !ok {
+		if record, ok = s.byTicker[name]; !ok {
+			record = &security.Record{Name: name}
+			s.byName[name] = record
+		}
+	}
+	s.used[record.Name] = record
+	return record
+}
+
+// id returns the SECID of the security.
+// QIF files don't have CUSIPs, so the ticker is used, or the name if there isn't one.
+func (s *securities) id(name string) secID {
+	record := s.find(name)
+	if record.Ticker != "" {
+		return secID{UniqueID: record.Ticker, UniqueIDType: "TICKER"}
+	}
+	return secID{UniqueID: record.Name, UniqueIDType: "NAME"}
+}
+
+func (s *securities) isMutualFund(name string) bool {
+	return strings.Contains(strings.ToLower(s.find(name).Type), "mutual")
+}
+
+// list returns the security list entries of the securities that were used.
+func (s *securities) list() []interface{} {
+	var list []interface{}
+	for _, name := range sortedKeys(s.used) {
+		record := s.used[name]
+		info := secInfo{SecID: s.id(name), SecName: truncate(record.Name, 120), Ticker: record.Ticker}
+		switch typ := strings.ToLower(record.Type); {
+		case strings.Contains(typ, "mutual"):
+			list = append(list, mfInfo{SecInfo: info})
+		case typ == "stock" || typ == "":
+			list = append(list, stockInfo{SecInfo: info})
+		default:
+			list = append(list, otherInfo{SecInfo: info})
+		}
+	}
+	return list
+}
+
+// incomeTypes maps the income actions to the INCOMETYPE.
+var incomeTypes = map[investment.Action]string{
+	investment.Div: "DIV", investment.DivX: "DIV",
+	investment.IntInc: "INTEREST", investment.IntIncX: "INTEREST",
+	investment.CGLong: "CGLONG", investment.CGLongX: "CGLONG",
+	investment.CGMid: "CGLONG", investment.CGMidX: "CGLONG",
+	investment.CGShort: "CGSHORT", investment.CGShortX: "CGSHORT",
+	investment.MiscInc: "MISC", investment.MiscIncX: "MISC",
+	investment.ReinvDiv: "DIV", investment.ReinvInt: "INTEREST",
+	investment.ReinvLg: "CGLONG", investment.ReinvMd: "CGLONG",
+	investment.ReinvSh: "CGSHORT", investment.ReinvCash: "MISC",
+}
+
+// investmentTransactions returns the transactions of an investment
+// statement with the first and last dates.
+//
+// The TOTAL of each transaction is the change in the cash of the account.
+// When an action moves cash to or from another account, an INVBANKTRAN
+// for the transfer is written after it so that the cash balances.
+func (s *securities) investmentTransactions(stmt *Statement) (list []interface{}, start, end string) {
+	ids := make(fitids)
+	for _, record := range stmt.Investments {
+		amount := record.Amount
+		if amount.IsZero() {
+			amount = record.AmountUCode
+		}
+		if amount.IsZero() && !record.Shares.IsZero() {
+			amount = record.Shares.Mul(record.Price).Add(record.Commission).Round(2)
+		}
+		dt := ofxDate(record.Date)
+		fitid := ids.next(stmt.Account.Name, record.Date, string(record.Action), record.Security, record.Shares.String(), amount.String(), record.Memo)
+		tran := invTran{FITID: fitid, DTTrade: dt, Memo: truncate(record.Memo, 255)}
+		buy := func(total decimal.Decimal) invBuy {
+			return invBuy{
+				InvTran:     tran,
+				SecID:       s.id(record.Security),
+				Units:       record.Shares.String(),
+				UnitPrice:   record.Price.String(),
+				Commission:  nonZero(record.Commission),
+				Total:       total.String(),
+				SubAcctSec:  "CASH",
+				SubAcctFund: "CASH",
+			}
+		}
+		cash := func(trnType string, total decimal.Decimal) invBankTran {
+			return invBankTran{StmtTrn: stmtTrn{
+				TrnType:  trnType,
+				DTPosted: dt,
+				TrnAmt:   total.String(),
+				FITID:    fitid,
+				Name:     truncate(record.Payee, 32),
+				Memo:     truncate(record.Memo, 255),
+			}, SubAcctFund: "CASH"}
+		}
+
+		var tran2 interface{}
+		transferIn := false // true if the transfer brings cash into the account
+		switch record.Action {
+		case investment.Buy, investment.BuyX, investment.CvrShrt:
+			buyType := "BUY"
+			if record.Action == investment.CvrShrt {
+				buyType = "BUYTOCOVER"
+			}
+			if s.isMutualFund(record.Security) {
+				tran2 = buyMF{InvBuy: buy(amount.Neg()), BuyType: buyType}
+			} else {
+				tran2 = buyStock{InvBuy: buy(amount.Neg()), BuyType: buyType}
+			}
+			transferIn = true
+		case investment.Sell, investment.SellX, investment.ShtSell:
+			sellType := "SELL"
+			if record.Action == investment.ShtSell {
+				sellType = "SELLSHORT"
+			}
+			if s.isMutualFund(record.Security) {
+				tran2 = sellMF{InvSell: buy(amount), SellType: sellType}
+			} else {
+				tran2 = sellStock{InvSell: buy(amount), SellType: sellType}
+			}
+		case investment.Div, investment.DivX, investment.IntInc, investment.IntIncX,
+			investment.CGLong, investment.CGLongX, investment.CGMid, investment.CGMidX,
+			investment.CGShort, investment.CGShortX, investment.MiscInc, investment.MiscIncX:
+			if record.Security == "" {
+				tran2 = cash("CREDIT", amount)
+			} else {
+				tran2 = income{
+					InvTran:     tran,
+					SecID:       s.id(record.Security),
+					IncomeType:  incomeTypes[record.Action],
+					Total:       amount.String(),
+					SubAcctSec:  "CASH",
+					SubAcctFund: "CASH",
+				}
+			}
+		case investment.ReinvDiv, investment.ReinvInt, investment.ReinvLg, investment.ReinvMd, investment.ReinvSh, investment.ReinvCash:
+			tran2 = reinvest{
+				InvTran:    tran,
+				SecID:      s.id(record.Security),
+				IncomeType: incomeTypes[record.Action],
+				Total:      amount.Neg().String(),
+				SubAcctSec: "CASH",
+				Units:      record.Shares.String(),
+				UnitPrice:  record.Price.String(),
+				Commission: nonZero(record.Commission),
+			}
+		case investment.RtrnCap, investment.RtrnCapX:
+			tran2 = retOfCap{InvTran: tran, SecID: s.id(record.Security), Total: amount.String(), SubAcctSec: "CASH", SubAcctFund: "CASH"}
+		case investment.MiscExp, investment.MiscExpX:
+			if record.Security == "" {
+				tran2 = cash("DEBIT", amount.Abs().Neg())
+			} else {
+				tran2 = invExpense{InvTran: tran, SecID: s.id(record.Security), Total: amount.Abs().Neg().String(), SubAcctSec: "CASH", SubAcctFund: "CASH"}
+			}
+			transferIn = true
+		case investment.MargInt, investment.MargIntX:
+			tran2 = marginInterest{InvTran: tran, Total: amount.Abs().Neg().String(), SubAcctFund: "CASH"}
+			transferIn = true
+		case investment.ShrsIn, investment.ShrsInX:
+			tran2 = transfer{InvTran: tran, SecID: s.id(record.Security), SubAcctSec: "CASH", Units: record.Shares.Abs().String(), TferAction: "IN", PosType: "LONG"}
+		case investment.ShrsOut, investment.ShrsOutX:
+			tran2 = transfer{InvTran: tran, SecID: s.id(record.Security), SubAcctSec: "CASH", Units: record.Shares.Abs().Neg().String(), TferAction: "OUT", PosType: "LONG"}
+		case investment.StkSplit:
+			numerator, denominator := ratio(record.Shares)
+			tran2 = split{InvTran: tran, SecID: s.id(record.Security), SubAcctSec: "CASH", OldUnits: "0", NewUnits: "0", Numerator: numerator, Denominator: denominator}
+		case investment.XIn, investment.ContribX:
+			tran2 = cash("XFER", amount.Abs())
+		case investment.XOut, investment.WithdrwX:
+			tran2 = cash("XFER", amount.Abs().Neg())
+		case investment.Cash:
+			if amount.Sign() < 0 {
+				tran2 = cash("DEBIT", amount)
+			} else {
+				tran2 = cash("CREDIT", amount)
+			}
+		default:
+			// reminders and employee stock options have no OFX transaction
+			continue
+		}
+		list = append(list, tran2)
+		start, end = minDate(start, dt), maxDate(end, dt)
+
+		// the actions that move cash directly between accounts are already transfers
+		switch record.Action {
+		case investment.XIn, investment.XOut, investment.ContribX, investment.WithdrwX, investment.ShrsInX, investment.ShrsOutX, investment.ExercisX:
+			continue
+		}
+		if !record.Action.IsTransfer() {
+			continue
+		}
+		transferAmount := record.TransferAmount.Abs()
+		if transferAmount.IsZero() {
+			transferAmount = amount.Abs()
+		}
+		if !transferIn {
+			transferAmount = transferAmount.Neg()
+		}
+		list = append(list, invBankTran{StmtTrn: stmtTrn{
+			TrnType:  "XFER",
+			DTPosted: dt,
+			TrnAmt:   transferAmount.String(),
+			FITID:    ids.next(fitid, "transfer"),
+			Name:     truncate(record.TransferAccount, 32),
+			Memo:     truncate(record.Memo, 255),
+		}, SubAcctFund: "CASH"})
+	}
+	return list, start, end
+}
+
+// ratio returns a split ratio as a numerator and denominator.
+// A ratio of 1.5 is three new shares for every two old shares.
+func ratio(d decimal.Decimal) (numerator, denominator string) {
+	d = d.Abs()
+	numerator = strings.TrimLeft(strings.Replace(d.String(), ".", "", 1), "0")
+	if numerator == "" {
+		numerator = "0"
+	}
+	return numerator, "1" + strings.Repeat("0", d.Scale())
+}
+
+// nonZero returns the amount, or an empty string if it is zero so that it is omitted.
+func nonZero(d decimal.Decimal) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,310 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package ofx writes the accounts and transactions read from a QIF file
+// as OFX 2.x bank, credit card and investment statement responses.
+package ofx
+
+import (
+	"encoding/xml"
+)
+
+// The elements of an OFX 2.x response.
+// Only the elements written by this package are defined.
+
+type document struct {
+	XMLName      xml.Name        `xml:"OFX"`
+	SignOn       signOn          `xml:"SIGNONMSGSRSV1>SONRS"`
+	Bank         *bankMsgs       `xml:"BANKMSGSRSV1,omitempty"`
+	CreditCard   *creditCardMsgs `xml:"CREDITCARDMSGSRSV1,omitempty"`
+	Investment   *investmentMsgs `xml:"INVSTMTMSGSRSV1,omitempty"`
+	SecurityList *secListMsgs    `xml:"SECLISTMSGSRSV1,omitempty"`
+}
+
+// The message sets are pointers because encoding/xml writes the
+// parents of an a>b path even when the child is omitted.
+
+type bankMsgs struct {
+	Statements []stmtTrnRs `xml:"STMTTRNRS"`
+}
+
+type creditCardMsgs struct {
+	Statements []ccStmtTrnRs `xml:"CCSTMTTRNRS"`
+}
+
+type investmentMsgs struct {
+	Statements []invStmtTrnRs `xml:"INVSTMTTRNRS"`
+}
+
+type secListMsgs struct {
+	SecList secList `xml:"SECLIST"`
+}
+
+type status struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+var ok = status{Code: 0, Severity: "INFO"}
+
+type signOn struct {
+	Status   status `xml:"STATUS"`
+	DTServer string `xml:"DTSERVER"`
+	Language string `xml:"LANGUAGE"`
+}
+
+type stmtTrnRs struct {
+	TrnUID string `xml:"TRNUID"`
+	Status status `xml:"STATUS"`
+	StmtRs stmtRs `xml:"STMTRS"`
+}
+
+type stmtRs struct {
+	CurDef       string       `xml:"CURDEF"`
+	BankAcctFrom bankAcct     `xml:"BANKACCTFROM"`
+	TranList     bankTranList `xml:"BANKTRANLIST"`
+	LedgerBal    balance      `xml:"LEDGERBAL"`
+}
+
+type ccStmtTrnRs struct {
+	TrnUID   string   `xml:"TRNUID"`
+	Status   status   `xml:"STATUS"`
+	CCStmtRs ccStmtRs `xml:"CCSTMTRS"`
+}
+
+type ccStmtRs struct {
+	CurDef     string       `xml:"CURDEF"`
+	CCAcctFrom ccAcct       `xml:"CCACCTFROM"`
+	TranList   bankTranList `xml:"BANKTRANLIST"`
+	LedgerBal  balance      `xml:"LEDGERBAL"`
+}
+
+type bankAcct struct {
+	BankID   string `xml:"BANKID"`
+	AcctID   string `xml:"ACCTID"`
+	AcctType string `xml:"ACCTTYPE"`
+}
+
+type ccAcct struct {
+	AcctID string `xml:"ACCTID"`
+}
+
+type bankTranList struct {
+	DTStart      string    `xml:"DTSTART"`
+	DTEnd        string    `xml:"DTEND"`
+	Transactions []stmtTrn `xml:"STMTTRN"`
+}
+
+type stmtTrn struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FITID    string `xml:"FITID"`
+	CheckNum string `xml:"CHECKNUM,omitempty"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+type balance struct {
+	BalAmt string `xml:"BALAMT"`
+	DTAsOf string `xml:"DTASOF"`
+}
+
+type invStmtTrnRs struct {
+	TrnUID    string    `xml:"TRNUID"`
+	Status    status    `xml:"STATUS"`
+	InvStmtRs invStmtRs `xml:"INVSTMTRS"`
+}
+
+type invStmtRs struct {
+	DTAsOf      string      `xml:"DTASOF"`
+	CurDef      string      `xml:"CURDEF"`
+	InvAcctFrom invAcct     `xml:"INVACCTFROM"`
+	TranList    invTranList `xml:"INVTRANLIST"`
+	InvBal      *invBal     `xml:"INVBAL,omitempty"`
+}
+
+type invAcct struct {
+	BrokerID string `xml:"BROKERID"`
+	AcctID   string `xml:"ACCTID"`
+}
+
+type invBal struct {
+	AvailCash     string `xml:"AVAILCASH"`
+	MarginBalance string `xml:"MARGINBALANCE"`
+	ShortBalance  string `xml:"SHORTBALANCE"`
+}
+
+// invTranList holds the investment transactions in the order they were read.
+// Each entry is one of the transaction elements below.
+type invTranList struct {
+	DTStart      string        `xml:"DTSTART"`
+	DTEnd        string        `xml:"DTEND"`
+	Transactions []interface{} `xml:",any"`
+}
+
+type invTran struct {
+	FITID   string `xml:"FITID"`
+	DTTrade string `xml:"DTTRADE"`
+	Memo    string `xml:"MEMO,omitempty"`
+}
+
+type secID struct {
+	UniqueID     string `xml:"UNIQUEID"`
+	UniqueIDType string `xml:"UNIQUEIDTYPE"`
+}
+
+type invBuy struct {
+	InvTran     invTran `xml:"INVTRAN"`
+	SecID       secID   `xml:"SECID"`
+	Units       string  `xml:"UNITS"`
+	UnitPrice   string  `xml:"UNITPRICE"`
+	Commission  string  `xml:"COMMISSION,omitempty"`
+	Total       string  `xml:"TOTAL"`
+	SubAcctSec  string  `xml:"SUBACCTSEC"`
+	SubAcctFund string  `xml:"SUBACCTFUND"`
+}
+
+type buyStock struct {
+	XMLName xml.Name `xml:"BUYSTOCK"`
+	InvBuy  invBuy   `xml:"INVBUY"`
+	BuyType string   `xml:"BUYTYPE"`
+}
+
+type buyMF struct {
+	XMLName xml.Name `xml:"BUYMF"`
+	InvBuy  invBuy   `xml:"INVBUY"`
+	BuyType string   `xml:"BUYTYPE"`
+}
+
+type sellStock struct {
+	XMLName  xml.Name `xml:"SELLSTOCK"`
+	InvSell  invBuy   `xml:"INVSELL"`
+	SellType string   `xml:"SELLTYPE"`
+}
+
+type sellMF struct {
+	XMLName  xml.Name `xml:"SELLMF"`
+	InvSell  invBuy   `xml:"INVSELL"`
+	SellType string   `xml:"SELLTYPE"`
+}
+
+type income struct {
+	XMLName     xml.Name `xml:"INCOME"`
+	InvTran     invTran  `xml:"INVTRAN"`
+	SecID       secID    `xml:"SECID"`
+	IncomeType  string   `xml:"INCOMETYPE"`
+	Total       string   `xml:"TOTAL"`
+	SubAcctSec  string   `xml:"SUBACCTSEC"`
+	SubAcctFund string   `xml:"SUBACCTFUND"`
+}
+
+type reinvest struct {
+	XMLName    xml.Name `xml:"REINVEST"`
+	InvTran    invTran  `xml:"INVTRAN"`
+	SecID      secID    `xml:"SECID"`
+	IncomeType string   `xml:"INCOMETYPE"`
+	Total      string   `xml:"TOTAL"`
+	SubAcctSec string   `xml:"SUBACCTSEC"`
+	Units      string   `xml:"UNITS"`
+	UnitPrice  string   `xml:"UNITPRICE"`
+	Commission string   `xml:"COMMISSION,omitempty"`
+}
+
+type retOfCap struct {
+	XMLName     xml.Name `xml:"RETOFCAP"`
+	InvTran     invTran  `xml:"INVTRAN"`
+	SecID       secID    `xml:"SECID"`
+	Total       string   `xml:"TOTAL"`
+	SubAcctSec  string   `xml:"SUBACCTSEC"`
+	SubAcctFund string   `xml:"SUBACCTFUND"`
+}
+
+type invExpense struct {
+	XMLName     xml.Name `xml:"INVEXPENSE"`
+	InvTran     invTran  `xml:"INVTRAN"`
+	SecID       secID    `xml:"SECID"`
+	Total       string   `xml:"TOTAL"`
+	SubAcctSec  string   `xml:"SUBACCTSEC"`
+	SubAcctFund string   `xml:"SUBACCTFUND"`
+}
+
+type marginInterest struct {
+	XMLName     xml.Name `xml:"MARGININTEREST"`
+	InvTran     invTran  `xml:"INVTRAN"`
+	Total       string   `xml:"TOTAL"`
+	SubAcctFund string   `xml:"SUBACCTFUND"`
+}
+
+type transfer struct {
+	XMLName    xml.Name `xml:"TRANSFER"`
+	InvTran    invTran  `xml:"INVTRAN"`
+	SecID      secID    `xml:"SECID"`
+	SubAcctSec string   `xml:"SUBACCTSEC"`
+	Units      string   `xml:"UNITS"`
+	TferAction string   `xml:"TFERACTION"`
+	PosType    string   `xml:"POSTYPE"`
+}
+
+type split struct {
+	XMLName     xml.Name `xml:"SPLIT"`
+	InvTran     invTran  `xml:"INVTRAN"`
+	SecID       secID    `xml:"SECID"`
+	SubAcctSec  string   `xml:"SUBACCTSEC"`
+	OldUnits    string   `xml:"OLDUNITS"`
+	NewUnits    string   `xml:"NEWUNITS"`
+	Numerator   string   `xml:"NUMERATOR"`
+	Denominator string   `xml:"DENOMINATOR"`
+}
+
+type invBankTran struct {
+	XMLName     xml.Name `xml:"INVBANKTRAN"`
+	StmtTrn     stmtTrn  `xml:"STMTTRN"`
+	SubAcctFund string   `xml:"SUBACCTFUND"`
+}
+
+type secList struct {
+	Securities []interface{} `xml:",any"`
+}
+
+type secInfo struct {
+	SecID   secID  `xml:"SECID"`
+	SecName string `xml:"SECNAME"`
+	Ticker  string `xml:"TICKER,omitempty"`
+}
+
+type stockInfo struct {
+	XMLName xml.Name `xml:"STOCKINFO"`
+	SecInfo secInfo  `xml:"SECINFO"`
+}
+
+type mfInfo struct {
+	XMLName xml.Name `xml:"MFINFO"`
+	SecInfo secInfo  `xml:"SECINFO"`
+}
+
+type otherInfo struct {
+	XMLName xml.Name `xml:"OTHERINFO"`
+	SecInfo secInfo  `xml:"SECINFO"`
+}
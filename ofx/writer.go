@@ -0,0 +1,368 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package ofx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/account"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of statement written for an account.
+type Kind string
+
+const (
+	Bank       Kind = "bank"
+	CreditCard Kind = "creditCard"
+	Investment Kind = "investment"
+)
+
+// Options control the statements that are written.
+type Options struct {
+	BankID         string // BANKID of bank accounts, defaults to "000000000"
+	BrokerID       string // BROKERID of investment accounts, defaults to "qif2json"
+	Currency       string // CURDEF of every statement, defaults to "USD"
+	DefaultAccount string // name of the account for transactions without one, defaults to "QIF"
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.BankID == "" {
+		opts.BankID = "000000000"
+	}
+	if opts.BrokerID == "" {
+		opts.BrokerID = "qif2json"
+	}
+	if opts.Currency == "" {
+		opts.Currency = "USD"
+	}
+	if opts.DefaultAccount == "" {
+		opts.DefaultAccount = "QIF"
+	}
+	return opts
+}
+
+// Statement is the transactions of a single account.
+type Statement struct {
+	Account      *account.Record
+	Kind         Kind
+	Transactions []*transaction.Record
+	Investments  []*investment.Record
+}
+
+// Statements returns a statement for every account that has transactions,
+// in the order of the account list. Accounts that aren't in the list come
+// last, in the order they were first seen.
+func Statements(r *reader.Reader, opts Options) []*Statement {
+	opts = opts.withDefaults()
+
+	var statements []*Statement
+	byName := make(map[string]*Statement)
+	if r.Accounts != nil {
+		for _, record := range r.Accounts.Records {
+			if _, ok := byName[record.Name]; !ok {
+				byName[record.Name] = &Statement{Account: record, Kind: kindOf(record.Type)}
+				statements = append(statements, byName[record.Name])
+			}
+		}
+	}
+	statementFor := func(name, typ string) *Statement {
+		if name == "" {
+			name = opts.DefaultAccount
+		}
+		s, ok := byName[name]
+		if !ok {
+			s = &Statement{Account: &account.Record{Name: name, Type: typ}, Kind: kindOf(typ)}
+			byName[name] = s
+			statements = append(statements, s)
+		}
+		return s
+	}
+	for _, record := range r.Transactions {
+		s := statementFor(record.Account, record.Type)
+		s.Transactions = append(s.Transactions, record)
+	}
+	for _, record := range r.Investments {
+		s := statementFor(record.Account, "Invst")
+		s.Kind = Investment
+		s.Investments = append(s.Investments, record)
+	}
+
+	// drop the accounts without transactions
+	var active []*Statement
+	for _, s := range statements {
+		if len(s.Transactions) != 0 || len(s.Investments) != 0 {
+			active = append(active, s)
+		}
+	}
+	return active
+}
+
+// kindOf returns the kind of statement for a Quicken account type.
+func kindOf(typ string) Kind {
+	switch typ {
+	case "CCard":
+		return CreditCard
+	case "Invst", "Port", "Mutual", "401(k)/403(b)":
+		return Investment
+	}
+	return Bank
+}
+
+// WriteBundle writes every statement of the reader to a single OFX file.
+func WriteBundle(w io.Writer, r *reader.Reader, opts Options) error {
+	return Write(w, Statements(r, opts), r.Securities, opts)
+}
+
+// Write writes the statements as a single OFX 2.x response.
+// The output only depends on the records, so writing the same
+// records twice produces the same file. Each transaction has a
+// FITID derived from a hash of its fields, so importing the file
+// again doesn't duplicate transactions.
+//
+// Investment actions that OFX can't express, such as Reminder
+// and the employee stock option actions, are not written.
+func Write(w io.Writer, statements []*Statement, securities *security.Section, opts Options) error {
+	opts = opts.withDefaults()
+
+	doc := document{SignOn: signOn{Status: ok, Language: "ENG"}}
+	var bank bankMsgs
+	var creditCard creditCardMsgs
+	var invest investmentMsgs
+	sec := newSecurities(securities)
+	for i, s := range statements {
+		trnUID := strconv.Itoa(i + 1)
+		switch s.Kind {
+		case Bank:
+			list, start, end, total := bankTransactions(s)
+			bank.Statements = append(bank.Statements, stmtTrnRs{TrnUID: trnUID, Status: ok, StmtRs: stmtRs{
+				CurDef:       opts.Currency,
+				BankAcctFrom: bankAcct{BankID: opts.BankID, AcctID: acctID(s.Account.Name), AcctType: acctType(s.Account.Type)},
+				TranList:     bankTranList{DTStart: start, DTEnd: end, Transactions: list},
+				LedgerBal:    ledgerBalance(s.Account, end, total),
+			}})
+			doc.SignOn.DTServer = maxDate(doc.SignOn.DTServer, end)
+		case CreditCard:
+			list, start, end, total := bankTransactions(s)
+			creditCard.Statements = append(creditCard.Statements, ccStmtTrnRs{TrnUID: trnUID, Status: ok, CCStmtRs: ccStmtRs{
+				CurDef:     opts.Currency,
+				CCAcctFrom: ccAcct{AcctID: acctID(s.Account.Name)},
+				TranList:   bankTranList{DTStart: start, DTEnd: end, Transactions: list},
+				LedgerBal:  ledgerBalance(s.Account, end, total),
+			}})
+			doc.SignOn.DTServer = maxDate(doc.SignOn.DTServer, end)
+		case Investment:
+			list, start, end := sec.investmentTransactions(s)
+			stmt := invStmtRs{
+				DTAsOf:      end,
+				CurDef:      opts.Currency,
+				InvAcctFrom: invAcct{BrokerID: opts.BrokerID, AcctID: acctID(s.Account.Name)},
+				TranList:    invTranList{DTStart: start, DTEnd: end, Transactions: list},
+			}
+			// the cash balance isn't known without a statement balance, and INVBAL is optional
+			if hasStatementBalance(s.Account) {
+				bal := ledgerBalance(s.Account, end, decimal.Decimal{})
+				stmt.DTAsOf = bal.DTAsOf
+				stmt.InvBal = &invBal{AvailCash: bal.BalAmt, MarginBalance: "0.00", ShortBalance: "0.00"}
+			}
+			invest.Statements = append(invest.Statements, invStmtTrnRs{TrnUID: trnUID, Status: ok, InvStmtRs: stmt})
+			doc.SignOn.DTServer = maxDate(doc.SignOn.DTServer, end)
+		}
+	}
+	if len(bank.Statements) != 0 {
+		doc.Bank = &bank
+	}
+	if len(creditCard.Statements) != 0 {
+		doc.CreditCard = &creditCard
+	}
+	if len(invest.Statements) != 0 {
+		doc.Investment = &invest
+	}
+	if list := sec.list(); len(list) != 0 {
+		doc.SecurityList = &secListMsgs{SecList: secList{Securities: list}}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`+"\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// bankTransactions returns the transactions of a bank or credit card
+// statement with the first and last dates and the total of the amounts.
+func bankTransactions(s *Statement) (list []stmtTrn, start, end string, total decimal.Decimal) {
+	ids := make(fitids)
+	for _, record := range s.Transactions {
+		amount := record.AmountTCode
+		if amount.IsZero() && len(record.Split) != 0 {
+			for _, split := range record.Split {
+				amount = amount.Add(split.AmountOrZero())
+			}
+		}
+		trn := stmtTrn{
+			TrnType:  trnType(record, amount),
+			DTPosted: ofxDate(record.Date),
+			TrnAmt:   amount.String(),
+			FITID:    ids.next(s.Account.Name, record.Date, amount.String(), record.Payee, record.RefNo, record.Memo),
+			Name:     truncate(record.Payee, 32),
+			Memo:     truncate(record.Memo, 255),
+		}
+		if isCheckNumber(record.RefNo) {
+			trn.CheckNum = record.RefNo
+		}
+		list = append(list, trn)
+		start, end = minDate(start, trn.DTPosted), maxDate(end, trn.DTPosted)
+		total = total.Add(amount)
+	}
+	return list, start, end, total
+}
+
+// trnType returns the TRNTYPE of a bank transaction.
+func trnType(record *transaction.Record, amount decimal.Decimal) string {
+	if isCheckNumber(record.RefNo) && amount.Sign() < 0 {
+		return "CHECK"
+	} else if record.ToAccount != "" {
+		return "XFER"
+	} else if amount.Sign() < 0 {
+		return "DEBIT"
+	}
+	return "CREDIT"
+}
+
+// ledgerBalance returns the balance from the statement balance of the account.
+// If the account doesn't have a statement date, the balance is as of the end date.
+// If it doesn't have a statement balance, the balance is the total of the
+// transactions, which is right when they start with the opening balance.
+func ledgerBalance(record *account.Record, end string, total decimal.Decimal) balance {
+	if !hasStatementBalance(record) {
+		return balance{BalAmt: total.String(), DTAsOf: end}
+	}
+	bal := balance{BalAmt: record.StatementBalance.String(), DTAsOf: end}
+	if record.StatementBalanceDate != "" {
+		bal.DTAsOf = ofxDate(record.StatementBalanceDate)
+	}
+	return bal
+}
+
+// hasStatementBalance returns true if the account has a statement balance or date.
+func hasStatementBalance(record *account.Record) bool {
+	return record.StatementBalanceDate != "" || !record.StatementBalance.IsZero()
+}
+
+// acctType returns the ACCTTYPE of a bank account.
+func acctType(typ string) string {
+	if typ == "Oth L" {
+		return "CREDITLINE"
+	}
+	return "CHECKING"
+}
+
+// acctID returns an ACCTID for the account, which is limited to 22 characters.
+func acctID(name string) string {
+	return truncate(name, 22)
+}
+
+// ofxDate converts a yyyy/mm/dd date to yyyymmdd.
+func ofxDate(date string) string {
+	return strings.ReplaceAll(date, "/", "")
+}
+
+func minDate(a, b string) string {
+	if a == "" || (b != "" && b < a) {
+		return b
+	}
+	return a
+}
+
+func maxDate(a, b string) string {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+func isCheckNumber(refNo string) bool {
+	if refNo == "" {
+		return false
+	}
+	for _, ch := range refNo {
+		if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// truncate returns at most n runes of the text.
+func truncate(text string, n int) string {
+	if r := []rune(text); len(r) > n {
+		return string(r[:n])
+	}
+	return text
+}
+
+// fitids creates the FITIDs of a statement.
+// A FITID is a hash of the fields of the transaction. Identical transactions
+// in the same account are numbered in the order they were read.
+type fitids map[string]int
+
+func (ids fitids) next(fields ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	id := hex.EncodeToString(sum[:12])
+	ids[id]++
+	if n := ids[id]; n > 1 {
+		return fmt.Sprintf("%s-%d", id, n)
+	}
+	return id
+}
+
+// sortedKeys returns the keys of the map in order.
+func sortedKeys(m map[string]*security.Record) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
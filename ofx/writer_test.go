@@ -0,0 +1,49 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package ofx
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/account"
+	"testing"
+)
+
+func TestLedgerBalance(t *testing.T) {
+	total := decimal.MustParse("-250.00")
+	for _, tc := range []struct {
+		name    string
+		account account.Record
+		want    balance
+	}{
+		{"no statement balance", account.Record{Name: "Checking"}, balance{BalAmt: "-250.00", DTAsOf: "20210131"}},
+		{"statement balance", account.Record{Name: "Checking", StatementBalance: decimal.MustParse("1234.50"), StatementBalanceDate: "2020/12/31"}, balance{BalAmt: "1234.50", DTAsOf: "20201231"}},
+		{"zero statement balance", account.Record{Name: "Checking", StatementBalanceDate: "2020/12/31"}, balance{BalAmt: "0", DTAsOf: "20201231"}},
+		{"statement balance without a date", account.Record{Name: "Checking", StatementBalance: decimal.MustParse("1234.50")}, balance{BalAmt: "1234.50", DTAsOf: "20210131"}},
+	} {
+		if got := ledgerBalance(&tc.account, "20210131", total); got != tc.want {
+			t.Errorf("%s: got %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -23,8 +23,11 @@
  */
 
 // Package qif defines the types to be imported from the QIF file
+// and writes the records read from a QIF file back to QIF.
 package qif
 
+import "github.com/mdhender/qif2json/decimal"
+
 // FILE is a structure that owns all of the data imported from the file.
 type FILE struct {
 	Accounts     []*ACCOUNT
@@ -35,7 +38,7 @@ type FILE struct {
 type ACCOUNT struct {
 	Name        string
 	Type        string
-	CreditLimit int
+	CreditLimit decimal.Decimal
 	Descr       string
 }
 
@@ -51,13 +54,13 @@ type TRANSACTION struct {
 
 // ENTRY is information about a single entry in a transaction
 type ENTRY struct {
-	From        string   `json:"from,omitempty"`
-	To          string   `json:"to,omitempty"`
-	Category    string   `json:"category,omitempty"`
-	Amount      int      `json:"amount,omitempty"`
-	Memo        string   `json:"memo,omitempty"`
-	FromAccount *ACCOUNT `json:"-"`
-	ToAccount   *ACCOUNT `json:"-"`
+	From        string          `json:"from,omitempty"`
+	To          string          `json:"to,omitempty"`
+	Category    string          `json:"category,omitempty"`
+	Amount      decimal.Decimal `json:"amount"`
+	Memo        string          `json:"memo,omitempty"`
+	FromAccount *ACCOUNT        `json:"-"`
+	ToAccount   *ACCOUNT        `json:"-"`
 }
 
 // File contains the data imported from the QIF file.
@@ -82,9 +85,9 @@ type File struct {
 type AccountDetail struct {
 	Name                 string
 	Type                 string
-	CreditLimit          int
+	CreditLimit          decimal.Decimal
 	Descr                string
-	StatementBalance     int
+	StatementBalance     decimal.Decimal
 	StatementBalanceDate string
 	Banks                []*BankDetail           `json:",omitempty"`
 	Budget               []*BudgetDetail         `json:",omitempty"`
@@ -98,8 +101,8 @@ type AccountDetail struct {
 // BankDetail is
 type BankDetail struct {
 	Address       []string // Up to five lines (the sixth line is an optional message)
-	AmountTCode   int
-	AmountUCode   int
+	AmountTCode   decimal.Decimal
+	AmountUCode   decimal.Decimal
 	Category      string // Category/Subcategory/Transfer/Class
 	ClearedStatus string
 	Date          string
@@ -131,8 +134,8 @@ type CategoryDetail struct {
 // CreditCardDetail is
 type CreditCardDetail struct {
 	Address       []string // Up to five lines (the sixth line is an optional message)
-	AmountTCode   int
-	AmountUCode   int
+	AmountTCode   decimal.Decimal
+	AmountUCode   decimal.Decimal
 	Category      string // Category/Subcategory/Transfer/Class
 	ClearedStatus string
 	Date          string
@@ -151,8 +154,8 @@ type InvestmentDetail struct {
 type MemorizedTransactionDetail struct {
 	Type                     string
 	Address                  []string // Up to five lines (the sixth line is an optional message)
-	AmountTCode              int
-	AmountUCode              int
+	AmountTCode              decimal.Decimal
+	AmountUCode              decimal.Decimal
 	Category                 string // Category/Subcategory/Transfer/Class
 	ClearedStatus            string
 	Date                     string
@@ -183,9 +186,9 @@ type PriceDetail struct {
 
 // Split allows a detail line to be split into multiple transfers
 type Split struct {
-	Amount   int    // Dollar amount of split
-	Category string // Category in split (Category/Transfer/Class)
-	Memo     string // in split
+	Amount   decimal.Decimal // Dollar amount of split
+	Category string          // Category in split (Category/Transfer/Class)
+	Memo     string          // in split
 }
 
 // SecurityDetail is
@@ -0,0 +1,368 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package qif
+
+import (
+	"bufio"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/account"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/class"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/reader/tag"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WriteOptions control how the QIF is written.
+type WriteOptions struct {
+	// DayFirst writes dates as dd/mm/yyyy instead of mm/dd/yyyy.
+	// The file must be read with a day first date grammar.
+	DayFirst bool
+}
+
+// Write writes the records as QIF.
+//
+// Reading the output with the default reader options returns the same
+// records, apart from their line and column numbers. Transactions are
+// written in the order of their line numbers, with an !Account block
+// whenever the account changes. Transactions without an account are
+// written first since there is no way to clear the account once it is set.
+//
+// The reader takes the first !Account section as the account list, so an
+// !Account block before the first transaction would be read as the list.
+// If the records have no account list but their transactions have accounts,
+// Write adds one, with each account once and the type of its first
+// transaction. Reading that output returns the same transactions, and an
+// account list the records didn't have.
+func Write(w io.Writer, r *reader.Reader, opts WriteOptions) error {
+	qw := writer{w: bufio.NewWriter(w), opts: opts}
+
+	// the first account section is the account list
+	accountTypes := make(map[string]string)
+	var accounts []*account.Record
+	if r.Accounts != nil {
+		accounts = r.Accounts.Records
+	}
+	for _, record := range accounts {
+		if _, ok := accountTypes[record.Name]; !ok {
+			accountTypes[record.Name] = record.Type
+		}
+	}
+	if len(accounts) == 0 {
+		// without an account list, the first !Account block would be read as the list
+		for _, record := range r.Transactions {
+			if _, ok := accountTypes[record.Account]; !ok && record.Account != "" {
+				accountTypes[record.Account] = record.Type
+				accounts = append(accounts, &account.Record{Name: record.Account, Type: record.Type})
+			}
+		}
+		for _, record := range r.Investments {
+			if _, ok := accountTypes[record.Account]; !ok && record.Account != "" {
+				accountTypes[record.Account] = "Invst"
+				accounts = append(accounts, &account.Record{Name: record.Account, Type: "Invst"})
+			}
+		}
+	}
+	if len(accounts) != 0 {
+		qw.header("Option:AutoSwitch")
+		qw.header("Account")
+		for _, record := range accounts {
+			qw.account(record)
+		}
+		qw.header("Clear:AutoSwitch")
+	}
+
+	if r.Classes != nil && len(r.Classes.Records) != 0 {
+		qw.header("Type:Class")
+		for _, record := range r.Classes.Records {
+			qw.class(record)
+		}
+	}
+	if r.Categories != nil && len(r.Categories.Records) != 0 {
+		qw.header("Type:Cat")
+		for _, record := range r.Categories.Records {
+			qw.category(record)
+		}
+	}
+	if r.Tags != nil && len(r.Tags.Records) != 0 {
+		qw.header("Type:Tag")
+		for _, record := range r.Tags.Records {
+			qw.tag(record)
+		}
+	}
+	if r.Securities != nil && len(r.Securities.Records) != 0 {
+		qw.header("Type:Security")
+		for _, record := range r.Securities.Records {
+			qw.security(record)
+		}
+	}
+	if len(r.Memorized) != 0 {
+		qw.header("Type:Memorized")
+		for _, record := range r.Memorized {
+			qw.transaction(record)
+		}
+	}
+	if len(r.Prices) != 0 {
+		qw.header("Type:Prices")
+		for _, record := range r.Prices {
+			qw.price(record)
+		}
+	}
+
+	// merge the bank and investment transactions back into the order they were read
+	type entry struct {
+		line        int
+		account     string
+		typ         string
+		transaction *transaction.Record
+		investment  *investment.Record
+	}
+	var entries []entry
+	for _, record := range r.Transactions {
+		entries = append(entries, entry{line: record.Line, account: record.Account, typ: record.Type, transaction: record})
+	}
+	for _, record := range r.Investments {
+		entries = append(entries, entry{line: record.Line, account: record.Account, typ: "Invst", investment: record})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if (entries[i].account == "") != (entries[j].account == "") {
+			return entries[i].account == ""
+		}
+		return entries[i].line < entries[j].line
+	})
+	var activeAccount, activeType string
+	for i, e := range entries {
+		if e.account != activeAccount {
+			typ := accountTypes[e.account]
+			if typ == "" {
+				typ = e.typ
+			}
+			qw.header("Account")
+			qw.account(&account.Record{Name: e.account, Type: typ})
+		}
+		if i == 0 || e.account != activeAccount || e.typ != activeType {
+			qw.header("Type:" + e.typ)
+		}
+		activeAccount, activeType = e.account, e.typ
+		if e.transaction != nil {
+			qw.transaction(e.transaction)
+		} else {
+			qw.investment(e.investment)
+		}
+	}
+
+	if qw.err != nil {
+		return qw.err
+	}
+	return qw.w.Flush()
+}
+
+// writer writes the fields of the records.
+// The first error is saved and later writes are ignored.
+type writer struct {
+	w    *bufio.Writer
+	opts WriteOptions
+	err  error
+}
+
+func (qw *writer) header(header string) {
+	qw.line("!" + header)
+}
+
+func (qw *writer) line(text string) {
+	if qw.err == nil {
+		_, qw.err = qw.w.WriteString(text + "\n")
+	}
+}
+
+func (qw *writer) endOfRecord() {
+	qw.line("^")
+}
+
+// field writes the field if the value isn't empty.
+func (qw *writer) field(flag, value string) {
+	if value != "" {
+		qw.line(flag + value)
+	}
+}
+
+// amount writes the field if the amount was set.
+// A zero amount with a scale was read from the input, so it is written.
+func (qw *writer) amount(flag string, value decimal.Decimal) {
+	if value != (decimal.Decimal{}) {
+		qw.line(flag + value.String())
+	}
+}
+
+// date writes the field if the date isn't empty.
+func (qw *writer) date(flag, value string) {
+	if value != "" {
+		qw.line(flag + qw.formatDate(value))
+	}
+}
+
+// formatDate converts a yyyy/mm/dd date to mm/dd/yyyy, or dd/mm/yyyy if the day is first.
+// Dates in any other form are returned unchanged.
+func (qw *writer) formatDate(value string) string {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 || len(parts[0]) != 4 {
+		return value
+	} else if qw.opts.DayFirst {
+		return parts[2] + "/" + parts[1] + "/" + parts[0]
+	}
+	return parts[1] + "/" + parts[2] + "/" + parts[0]
+}
+
+// withClass returns the text of an L or S field.
+func withClass(text, class string) string {
+	if class == "" {
+		return text
+	}
+	return text + "/" + class
+}
+
+func (qw *writer) account(record *account.Record) {
+	qw.field("N", record.Name)
+	qw.field("T", record.Type)
+	qw.field("D", record.Description)
+	qw.amount("L", record.CreditLimit)
+	qw.amount("$", record.StatementBalance)
+	qw.date("/", record.StatementBalanceDate)
+	qw.endOfRecord()
+}
+
+func (qw *writer) category(record *category.Record) {
+	qw.field("N", record.Name)
+	qw.field("D", record.Description)
+	if record.IsTaxRelated {
+		qw.line("T")
+	}
+	if record.IsIncome {
+		qw.line("I")
+	} else {
+		qw.line("E")
+	}
+	qw.field("R", record.TaxSchedule)
+	for _, amount := range record.BudgetAmount {
+		qw.line("B" + amount.String())
+	}
+	qw.endOfRecord()
+}
+
+func (qw *writer) class(record *class.Record) {
+	qw.field("N", record.Name)
+	qw.field("D", record.Description)
+	qw.endOfRecord()
+}
+
+func (qw *writer) security(record *security.Record) {
+	qw.field("N", record.Name)
+	qw.field("S", record.Ticker)
+	qw.field("T", record.Type)
+	qw.field("G", record.Risk)
+	qw.field("D", record.Description)
+	qw.endOfRecord()
+}
+
+func (qw *writer) tag(record *tag.Record) {
+	qw.field("N", record.Name)
+	qw.field("D", record.Description)
+	qw.endOfRecord()
+}
+
+func (qw *writer) price(record *transaction.Record) {
+	qw.line(`"` + record.Ticker + `",` + record.Price.String() + `,"` + qw.formatDate(record.Date) + `"`)
+	qw.endOfRecord()
+}
+
+func (qw *writer) transaction(record *transaction.Record) {
+	qw.field("K", record.MemorizedFlag)
+	qw.date("D", record.Date)
+	qw.amount("T", record.AmountTCode)
+	qw.amount("U", record.AmountUCode)
+	qw.field("C", record.ClearedStatus)
+	qw.field("N", record.RefNo)
+	qw.field("P", record.Payee)
+	qw.field("M", record.Memo)
+	for _, line := range record.Address {
+		qw.line("A" + line)
+	}
+	if record.ToAccount != "" {
+		qw.line("L" + withClass("["+record.ToAccount+"]", record.Class))
+	} else if record.Category != "" || record.Class != "" {
+		qw.line("L" + withClass(record.Category, record.Class))
+	}
+	qw.field("Y", record.Ticker)
+	qw.amount("I", record.Interest)
+	qw.amount("Q", record.Quantity)
+	qw.amount("O", record.Commission)
+	for _, split := range record.Split {
+		if split.Account != "" {
+			qw.line("S" + withClass("["+split.Account+"]", split.Class))
+		} else {
+			qw.line("S" + withClass(split.Category, split.Class))
+		}
+		if split.Amount != nil {
+			qw.line("$" + split.Amount.String())
+		}
+		qw.field("E", split.Memo)
+	}
+	if a := record.Amortization; a != nil {
+		qw.date("1", a.FirstPaymentDate)
+		qw.amount("2", a.Years)
+		qw.amount("3", a.PaymentsMade)
+		qw.amount("4", a.PeriodsPerYear)
+		qw.amount("5", a.InterestRate)
+		qw.amount("6", a.CurrentBalance)
+		qw.amount("7", a.OriginalAmount)
+	}
+	qw.endOfRecord()
+}
+
+func (qw *writer) investment(record *investment.Record) {
+	qw.date("D", record.Date)
+	qw.field("N", string(record.Action))
+	qw.field("Y", record.Security)
+	qw.amount("I", record.Price)
+	qw.amount("Q", record.Shares)
+	qw.amount("O", record.Commission)
+	qw.amount("T", record.Amount)
+	qw.amount("U", record.AmountUCode)
+	qw.field("C", record.ClearedStatus)
+	qw.field("P", record.Payee)
+	qw.field("M", record.Memo)
+	if record.TransferAccount != "" {
+		qw.line("L" + withClass("["+record.TransferAccount+"]", record.Class))
+	} else if record.Category != "" || record.Class != "" {
+		qw.line("L" + withClass(record.Category, record.Class))
+	}
+	qw.amount("$", record.TransferAmount)
+	qw.endOfRecord()
+}
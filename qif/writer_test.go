@@ -0,0 +1,152 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package qif
+
+import (
+	"bytes"
+	"github.com/mdhender/qif2json/date"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// TestWriteRoundTrip checks that reading the output of Write returns the records that were written.
+func TestWriteRoundTrip(t *testing.T) {
+	input, err := ioutil.ReadFile("testdata/roundtrip.qif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dayFirst := reader.Options{Dates: date.Grammar{Pivot: date.DefaultPivot, DayFirst: true}}
+	want, err := reader.ReadFrom(bytes.NewReader(input), dayFirst)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	// guard against a fixture that reads as nothing, which would round-trip trivially
+	if want.Accounts == nil || want.Categories == nil || want.Classes == nil || want.Securities == nil || want.Tags == nil ||
+		len(want.Transactions) == 0 || len(want.Investments) == 0 || len(want.Memorized) == 0 || len(want.Prices) == 0 {
+		t.Fatalf("fixture is missing sections")
+	}
+	clearPositions(reflect.ValueOf(want))
+
+	for _, tc := range []struct {
+		name  string
+		write WriteOptions
+		read  reader.Options
+	}{
+		{"day first", WriteOptions{DayFirst: true}, dayFirst},
+		{"month first", WriteOptions{}, reader.Options{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, want, tc.write); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			got, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()), tc.read)
+			if err != nil {
+				t.Fatalf("read output: %v\n%s", err, buf.String())
+			}
+			clearPositions(reflect.ValueOf(got))
+			for _, section := range []struct {
+				name      string
+				got, want interface{}
+			}{
+				{"accounts", got.Accounts, want.Accounts},
+				{"categories", got.Categories, want.Categories},
+				{"classes", got.Classes, want.Classes},
+				{"securities", got.Securities, want.Securities},
+				{"tags", got.Tags, want.Tags},
+				{"transactions", got.Transactions, want.Transactions},
+				{"investments", got.Investments, want.Investments},
+				{"memorized", got.Memorized, want.Memorized},
+				{"prices", got.Prices, want.Prices},
+				{"diagnostics", got.Diagnostics, want.Diagnostics},
+			} {
+				if !reflect.DeepEqual(section.got, section.want) {
+					t.Errorf("%s: records differ after the round trip\n%s", section.name, buf.String())
+				}
+			}
+		})
+	}
+}
+
+// clearPositions sets the Line and Col fields of every record to zero,
+// since they depend on where the record is in the file.
+func clearPositions(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			clearPositions(v.Elem())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			clearPositions(v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			switch name := v.Type().Field(i).Name; {
+			case (name == "Line" || name == "Col") && v.Field(i).Kind() == reflect.Int && v.Field(i).CanSet():
+				v.Field(i).SetInt(0)
+			case v.Field(i).CanSet():
+				clearPositions(v.Field(i))
+			}
+		}
+	}
+}
+
+// TestWriteWithoutAccountList checks that Write adds an account list
+// when the records don't have one, so transactions keep their accounts.
+func TestWriteWithoutAccountList(t *testing.T) {
+	want := &reader.Reader{Transactions: []*transaction.Record{
+		{Line: 1, Type: "Bank", Date: "2021/01/02", AmountTCode: decimal.MustParse("-10.00"), Payee: "Unfiled"},
+		{Line: 2, Account: "Checking", Type: "Bank", Date: "2021/01/03", AmountTCode: decimal.MustParse("-20.00"), Payee: "Grocer"},
+		{Line: 3, Account: "Visa", Type: "CCard", Date: "2021/01/04", AmountTCode: decimal.MustParse("-30.00"), Payee: "Garage"},
+		{Line: 4, Account: "Checking", Type: "Bank", Date: "2021/01/05", AmountTCode: decimal.MustParse("40.00"), Payee: "Employer"},
+	}}
+	var buf bytes.Buffer
+	if err := Write(&buf, want, WriteOptions{}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := reader.ReadFrom(bytes.NewReader(buf.Bytes()), reader.Options{})
+	if err != nil {
+		t.Fatalf("read output: %v\n%s", err, buf.String())
+	}
+	clearPositions(reflect.ValueOf(got))
+	clearPositions(reflect.ValueOf(want))
+	if !reflect.DeepEqual(got.Transactions, want.Transactions) {
+		t.Errorf("transactions differ after the round trip\n%s", buf.String())
+	}
+	var accounts []string
+	if got.Accounts != nil {
+		for _, record := range got.Accounts.Records {
+			accounts = append(accounts, record.Name+" "+record.Type)
+		}
+	}
+	if want := []string{"Checking Bank", "Visa CCard"}; !reflect.DeepEqual(accounts, want) {
+		t.Errorf("accounts: got %q, want %q", accounts, want)
+	}
+}
@@ -25,8 +25,8 @@
 package account
 
 import (
-	"fmt"
 	"github.com/mdhender/qif2json/buffer"
+	"github.com/mdhender/qif2json/decimal"
 )
 
 type Section struct {
@@ -38,10 +38,10 @@ type Section struct {
 type Record struct {
 	Line                 int `json:"-"`
 	Col                  int `json:"-"`
-	CreditLimit          string
+	CreditLimit          decimal.Decimal
 	Description          string
 	Name                 string
-	StatementBalance     string
+	StatementBalance     decimal.Decimal
 	StatementBalanceDate string
 	Type                 string
 }
@@ -61,7 +61,7 @@ func ReadSection(buf buffer.Buffer) (*Section, buffer.Buffer, error) {
 		var record *Record
 		record, buf, err = ReadRecord(buf)
 		if err != nil {
-			return nil, buf, fmt.Errorf("%d: %s: %w", section.Line, sname, err)
+			return nil, buf, err
 		} else if record == nil {
 			break
 		}
@@ -71,7 +71,7 @@ func ReadSection(buf buffer.Buffer) (*Section, buffer.Buffer, error) {
 	// read the end of section marker
 	eos, bb := buf.EndOfSection()
 	if eos == nil {
-		return nil, saved, fmt.Errorf("%d: %s: %d:%d: unexpected input", section.Line, sname, buf.Line, buf.Col)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrUnexpectedInput}
 	}
 	buf = bb
 
@@ -82,11 +82,15 @@ func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
 	saved, sname, record := buf, "account", Record{Line: buf.Line, Col: buf.Col}
 
 	var found bool
-	var creditLimit, descr, name, statementBalance, statementBalanceDate, typ []byte
+	var descr, name, statementBalanceDate, typ []byte
+	var creditLimit, statementBalance *decimal.Decimal
 	for {
 		if creditLimit == nil {
-			if creditLimit, buf = buf.Field("L"); creditLimit != nil {
-				found, record.CreditLimit = true, string(creditLimit)
+			var err error
+			if creditLimit, buf, err = buf.Amount("L"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "credit limit")
+			} else if creditLimit != nil {
+				found, record.CreditLimit = true, *creditLimit
 				continue
 			}
 		}
@@ -103,13 +107,19 @@ func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
 			}
 		}
 		if statementBalance == nil {
-			if statementBalance, buf = buf.Field("$"); statementBalance != nil {
-				found, record.StatementBalance = true, string(statementBalance)
+			var err error
+			if statementBalance, buf, err = buf.Amount("$"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "statement balance")
+			} else if statementBalance != nil {
+				found, record.StatementBalance = true, *statementBalance
 				continue
 			}
 		}
 		if statementBalanceDate == nil {
-			if statementBalanceDate, buf = buf.Date("/"); statementBalanceDate != nil {
+			var err error
+			if statementBalanceDate, buf, err = buf.Date("/"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "statement date")
+			} else if statementBalanceDate != nil {
 				found, record.StatementBalanceDate = true, string(statementBalanceDate)
 				continue
 			}
@@ -130,12 +140,12 @@ func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
 
 	// check for required fields
 	if name == nil {
-		return nil, saved, fmt.Errorf("%d: %s: missing field %q", record.Line, sname, "name")
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "name", Err: buffer.ErrMissingField}
 	}
 
 	eor, bb := buf.EndOfRecord()
 	if eor == nil {
-		return nil, saved, fmt.Errorf("%d: %s: missing record terminator", buf.Line, sname)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrMissingTerminator}
 	}
 	buf = bb
 
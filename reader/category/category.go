@@ -25,8 +25,8 @@
 package category
 
 import (
-	"fmt"
 	"github.com/mdhender/qif2json/buffer"
+	"github.com/mdhender/qif2json/decimal"
 )
 
 type Section struct {
@@ -38,7 +38,7 @@ type Section struct {
 type Record struct {
 	Line         int
 	Col          int
-	BudgetAmount []string
+	BudgetAmount []decimal.Decimal
 	Description  string
 	IsIncome     bool // quicken assumes default is expense category
 	IsTaxRelated bool
@@ -61,7 +61,7 @@ func ReadSection(buf buffer.Buffer) (*Section, buffer.Buffer, error) {
 		var record *Record
 		record, buf, err = ReadRecord(buf)
 		if err != nil {
-			return nil, buf, fmt.Errorf("%d: %s: %w", section.Line, sname, err)
+			return nil, buf, err
 		} else if record == nil {
 			break
 		}
@@ -71,7 +71,7 @@ func ReadSection(buf buffer.Buffer) (*Section, buffer.Buffer, error) {
 	// read the end of section marker
 	eos, bb := buf.EndOfSection()
 	if eos == nil {
-		return nil, saved, fmt.Errorf("%d: %s: %d:%d: unexpected input", section.Line, sname, buf.Line, buf.Col)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrUnexpectedInput}
 	}
 	buf = bb
 
@@ -82,10 +82,13 @@ func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
 	saved, sname, record := buf, "category", Record{Line: buf.Line, Col: buf.Col}
 
 	var found bool
-	var budgetAmount, descr, expense, income, name, taxRelated, taxSchedule []byte
+	var descr, expense, income, name, taxRelated, taxSchedule []byte
 	for {
-		if budgetAmount, buf = buf.Field("B"); budgetAmount != nil {
-			found, record.BudgetAmount = true, append(record.BudgetAmount, string(budgetAmount))
+		if budgetAmount, bb, err := buf.Amount("B"); err != nil {
+			return nil, saved, buf.FieldError(err, sname, "budget amount")
+		} else if budgetAmount != nil {
+			found, record.BudgetAmount = true, append(record.BudgetAmount, *budgetAmount)
+			buf = bb
 			continue
 		}
 		if descr == nil {
@@ -115,7 +118,7 @@ func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
 		}
 		if taxRelated == nil {
 			if taxRelated, buf = buf.Field("T"); taxRelated != nil {
-				found, record.IsTaxRelated = true, false
+				found, record.IsTaxRelated = true, true
 				continue
 			}
 		}
@@ -136,12 +139,12 @@ func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
 
 	// check for required fields
 	if name == nil {
-		return nil, saved, fmt.Errorf("%d: %s: missing field %q", record.Line, sname, "name")
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "name", Err: buffer.ErrMissingField}
 	}
 
 	eor, bb := buf.EndOfRecord()
 	if eor == nil {
-		return nil, saved, fmt.Errorf("%d: %s: missing record terminator", buf.Line, sname)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrMissingTerminator}
 	}
 	buf = bb
 
@@ -0,0 +1,120 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package category
+
+import (
+	"strings"
+)
+
+// Node is a category in the category hierarchy.
+// Quicken separates a subcategory from its parent with a colon,
+// so "Auto:Fuel" is the child "Fuel" of the category "Auto".
+type Node struct {
+	Name     string  // the last part of the path, e.g. "Fuel"
+	Path     string  // the full name, e.g. "Auto:Fuel"
+	Depth    int     // zero for a top level category
+	IsIncome bool    // from the record, or from the first child if synthesized
+	Parent   *Node   // nil for a top level category
+	Children []*Node // in the order they were first seen
+	Record   *Record // nil if the category was synthesized
+}
+
+// Synthesized returns true if the category was not in the category list
+// but was created as the parent of one that was.
+func (n *Node) Synthesized() bool {
+	return n.Record == nil
+}
+
+// Tree is the category hierarchy.
+type Tree struct {
+	Roots []*Node // the top level categories, in the order they were first seen
+	nodes map[string]*Node
+}
+
+// Tree returns the hierarchy of the categories in the section.
+func (s *Section) Tree() *Tree {
+	return NewTree(s.Records)
+}
+
+// NewTree returns the hierarchy of the categories.
+// Parents that are missing from the records are synthesized.
+// If a name appears more than once, the first record is used.
+func NewTree(records []*Record) *Tree {
+	t := Tree{nodes: make(map[string]*Node)}
+	for _, record := range records {
+		node := t.add(record.Name, record.IsIncome)
+		if node.Record == nil {
+			node.Record, node.IsIncome = record, record.IsIncome
+		}
+	}
+	return &t
+}
+
+// Find returns the category with the given full name, or nil if there isn't one.
+func (t *Tree) Find(path string) *Node {
+	return t.nodes[path]
+}
+
+// Walk calls fn for every category in the tree, parents before children.
+func (t *Tree) Walk(fn func(*Node)) {
+	var walk func(nodes []*Node)
+	walk = func(nodes []*Node) {
+		for _, node := range nodes {
+			fn(node)
+			walk(node.Children)
+		}
+	}
+	walk(t.Roots)
+}
+
+// Add returns the category with the given full name, synthesizing it and
+// any missing parents. A synthesized category is income if its closest
+// existing parent is, otherwise isIncome is used.
+func (t *Tree) Add(path string, isIncome bool) *Node {
+	for i := strings.LastIndexByte(path, ':'); i != -1; i = strings.LastIndexByte(path[:i], ':') {
+		if parent, ok := t.nodes[path[:i]]; ok {
+			isIncome = parent.IsIncome
+			break
+		}
+	}
+	return t.add(path, isIncome)
+}
+
+// add returns the node for the path, creating it and any missing parents.
+func (t *Tree) add(path string, isIncome bool) *Node {
+	if node, ok := t.nodes[path]; ok {
+		return node
+	}
+	node := &Node{Name: path, Path: path, IsIncome: isIncome}
+	if i := strings.LastIndexByte(path, ':'); i == -1 {
+		t.Roots = append(t.Roots, node)
+	} else {
+		node.Name, node.Parent = path[i+1:], t.add(path[:i], isIncome)
+		node.Depth = node.Parent.Depth + 1
+		node.Parent.Children = append(node.Parent.Children, node)
+	}
+	t.nodes[path] = node
+	return node
+}
@@ -0,0 +1,104 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package category
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewTree(t *testing.T) {
+	records := []*Record{
+		{Name: "Auto:Fuel"},
+		{Name: "Salary", IsIncome: true},
+		{Name: "Auto", Description: "Car"},
+		{Name: "Auto:Service:Parts"},
+		{Name: "Salary", Description: "duplicate"},
+		{Name: "Bonus:Annual", IsIncome: true},
+	}
+	tree := NewTree(records)
+
+	// parents before children, in the order first seen
+	var got []string
+	tree.Walk(func(n *Node) {
+		got = append(got, n.Path)
+	})
+	want := []string{"Auto", "Auto:Fuel", "Auto:Service", "Auto:Service:Parts", "Salary", "Bonus", "Bonus:Annual"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk: got %q, want %q", got, want)
+	}
+
+	for _, tc := range []struct {
+		path        string
+		name        string
+		depth       int
+		parent      string
+		income      bool
+		synthesized bool
+		record      *Record
+	}{
+		{"Auto", "Auto", 0, "", false, false, records[2]},
+		{"Auto:Service", "Service", 1, "Auto", false, true, nil},
+		{"Auto:Service:Parts", "Parts", 2, "Auto:Service", false, false, records[3]},
+		{"Salary", "Salary", 0, "", true, false, records[1]},
+		{"Bonus", "Bonus", 0, "", true, true, nil},
+	} {
+		n := tree.Find(tc.path)
+		if n == nil {
+			t.Errorf("Find(%q): not found", tc.path)
+			continue
+		}
+		var parent string
+		if n.Parent != nil {
+			parent = n.Parent.Path
+		}
+		if n.Name != tc.name || n.Depth != tc.depth || parent != tc.parent || n.IsIncome != tc.income || n.Synthesized() != tc.synthesized || n.Record != tc.record {
+			t.Errorf("Find(%q): got %q depth %d parent %q income %v synthesized %v, want %q depth %d parent %q income %v synthesized %v",
+				tc.path, n.Name, n.Depth, parent, n.IsIncome, n.Synthesized(), tc.name, tc.depth, tc.parent, tc.income, tc.synthesized)
+		}
+	}
+	if n := tree.Find("Fuel"); n != nil {
+		t.Errorf("Find(%q): got %q, want nil", "Fuel", n.Path)
+	}
+}
+
+func TestTreeAdd(t *testing.T) {
+	tree := NewTree([]*Record{{Name: "Salary", IsIncome: true}, {Name: "Auto"}})
+
+	// a new category is income if its closest existing parent is
+	if n := tree.Add("Salary:Bonus:Annual", false); !n.IsIncome || !n.Synthesized() || !n.Parent.IsIncome {
+		t.Errorf("Add: got income %v, parent income %v, want both true", n.IsIncome, n.Parent.IsIncome)
+	}
+	if n := tree.Add("Auto:Fuel", true); n.IsIncome {
+		t.Errorf("Add: got income %v for a subcategory of an expense", n.IsIncome)
+	}
+	if n := tree.Add("Gifts", true); !n.IsIncome || n.Depth != 0 || len(tree.Roots) != 3 {
+		t.Errorf("Add: got income %v depth %d with %d roots, want a new income root", n.IsIncome, n.Depth, len(tree.Roots))
+	}
+	// adding an existing category returns it unchanged
+	if n := tree.Add("Auto", true); n != tree.Find("Auto") || n.IsIncome || n.Synthesized() {
+		t.Errorf("Add: existing category changed")
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package class reads the records of a !Type:Class section.
+package class
+
+import (
+	"github.com/mdhender/qif2json/buffer"
+	"strings"
+)
+
+type Section struct {
+	Line    int       `json:"-"`
+	Col     int       `json:"-"`
+	Records []*Record `json:"records,omitempty"`
+}
+
+type Record struct {
+	Line        int    `json:"-"`
+	Col         int    `json:"-"`
+	Description string `json:"descr,omitempty"`
+	Name        string `json:"name"`
+}
+
+// Cut splits the text of an L or S field into the category and the class.
+// Quicken writes a class after the category as "Category:Subcategory/Class:Subclass".
+// A transfer may have a class, too, as in "[Checking]/Class".
+// If there is no class, the class is empty.
+func Cut(text string) (category, class string) {
+	if i := strings.IndexByte(text, '/'); i != -1 {
+		return text[:i], text[i+1:]
+	}
+	return text, ""
+}
+
+func ReadSection(buf buffer.Buffer) (*Section, buffer.Buffer, error) {
+	saved, sname, section := buf, "classes", Section{Line: buf.Line, Col: buf.Col}
+
+	lit, bb := buf.Literal("!Type:Class")
+	if lit == nil {
+		return nil, saved, nil
+	}
+	buf = bb
+
+	// read the section detail
+	var err error
+	for {
+		var record *Record
+		record, buf, err = ReadRecord(buf)
+		if err != nil {
+			return nil, buf, err
+		} else if record == nil {
+			break
+		}
+		section.Records = append(section.Records, record)
+	}
+
+	// read the end of section marker
+	eos, bb := buf.EndOfSection()
+	if eos == nil {
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrUnexpectedInput}
+	}
+	buf = bb
+
+	return &section, buf, nil
+}
+
+func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
+	saved, sname, record := buf, "class", Record{Line: buf.Line, Col: buf.Col}
+
+	var found bool
+	var descr, name []byte
+	for {
+		if descr == nil {
+			if descr, buf = buf.Field("D"); descr != nil {
+				found, record.Description = true, string(descr)
+				continue
+			}
+		}
+		if name == nil {
+			if name, buf = buf.Field("N"); name != nil {
+				found, record.Name = true, string(name)
+				continue
+			}
+		}
+
+		break
+	}
+
+	if !found { // no fields found
+		return nil, saved, nil
+	}
+
+	// check for required fields
+	if name == nil {
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "name", Err: buffer.ErrMissingField}
+	}
+
+	eor, bb := buf.EndOfRecord()
+	if eor == nil {
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrMissingTerminator}
+	}
+	buf = bb
+
+	return &record, buf, nil
+}
@@ -0,0 +1,48 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package class
+
+import "testing"
+
+func TestCut(t *testing.T) {
+	for _, tc := range []struct {
+		text            string
+		category, class string
+	}{
+		{"Auto:Fuel", "Auto:Fuel", ""},
+		{"Auto:Fuel/Business", "Auto:Fuel", "Business"},
+		{"Auto/Business:Travel", "Auto", "Business:Travel"},
+		{"[Checking]/Business", "[Checking]", "Business"},
+		{"/Business", "", "Business"},
+		{"Auto/", "Auto", ""},
+		{"Auto/Home/Garage", "Auto", "Home/Garage"},
+		{"", "", ""},
+	} {
+		category, class := Cut(tc.text)
+		if category != tc.category || class != tc.class {
+			t.Errorf("Cut(%q): got %q %q, want %q %q", tc.text, category, class, tc.category, tc.class)
+		}
+	}
+}
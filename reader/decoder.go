@@ -0,0 +1,285 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package reader
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mdhender/qif2json/buffer"
+	"github.com/mdhender/qif2json/date"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/account"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/class"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/reader/tag"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"io"
+	"strings"
+)
+
+// Decoder reads records from a QIF input one at a time.
+// Only the current record is held in memory.
+type Decoder struct {
+	stream  *buffer.Stream
+	section struct {
+		header  string // header of the active section, without the '!'
+		name    string // name of the section used in error messages
+		line    int
+		col     int
+		skipped bool // true if the section is not supported
+	}
+	active struct {
+		account     string
+		accountType string
+		record      *account.Record // the record that set the account in the active section
+	}
+	// the first account section with records is the account list.
+	// later account sections set the account for the transactions that follow,
+	// and in recovery mode all but the last record of such a section is a warning.
+	accountList struct {
+		reading bool
+		done    bool
+	}
+	recover     bool
+	diagnostics []Diagnostic
+}
+
+// Options control how the input is parsed.
+type Options struct {
+	// Dates parses every date in the input. If nil, date.Default is used.
+	Dates date.Parser
+	// Numbers parses every amount, price and quantity in the input.
+	// If nil, decimal.US is used. Use a new decimal.Auto for each input,
+	// after calling its Prescan, to detect the locale of the input.
+	Numbers decimal.Parser
+	// Recover turns errors in the input into diagnostics.
+	// A record that can't be parsed is dropped and reading resumes
+	// with the next record or section. The records of a section
+	// that isn't supported are skipped.
+	Recover bool
+}
+
+// NewDecoder returns a decoder that reads from the input.
+func NewDecoder(r io.Reader, opts Options) *Decoder {
+	d := Decoder{stream: buffer.NewStream(r), recover: opts.Recover}
+	d.stream.Dates, d.stream.Numbers = opts.Dates, opts.Numbers
+	return &d
+}
+
+// Next returns the next record from the input. The record will be an
+// *account.Record, *category.Record, *class.Record, *security.Record, *tag.Record or *transaction.Record.
+// The records of an investment section are returned as *investment.Record.
+// Memorized transactions and prices are returned as *transaction.Record with
+// a Type of "Memorized" or "Prices".
+// It returns io.EOF when the input is exhausted.
+func (d *Decoder) Next() (interface{}, error) {
+	for {
+		chunk, err := d.stream.Next()
+		if err != nil {
+			var pe *buffer.ParseError
+			if errors.As(err, &pe) && pe.Section == "" {
+				pe.Section = d.section.name
+			}
+			if d.recover && pe != nil {
+				d.diagnose(err, SeverityError)
+				continue
+			}
+			return nil, err
+		}
+		if chunk.Buffer[0] == '!' {
+			if err := d.header(chunk); err != nil {
+				if !d.recover {
+					return nil, err
+				}
+				d.diagnose(err, SeverityWarning)
+			}
+			continue
+		}
+		record, err := d.record(chunk)
+		if err != nil {
+			// a record reader names the record it was reading, which is more specific
+			var pe *buffer.ParseError
+			if errors.As(err, &pe) && pe.Section == "" {
+				pe.Section = d.section.name
+			}
+			if !d.recover {
+				return nil, err
+			}
+			d.diagnose(err, SeverityError)
+			continue
+		} else if record != nil {
+			return record, nil
+		}
+	}
+}
+
+// Recover returns true if the decoder is in recovery mode.
+func (d *Decoder) Recover() bool {
+	return d.recover
+}
+
+// Section returns the header and position of the active section.
+func (d *Decoder) Section() (header string, line, col int) {
+	return d.section.header, d.section.line, d.section.col
+}
+
+// Diagnostics returns the problems found in the input so far.
+// Diagnostics are only collected in recovery mode.
+func (d *Decoder) Diagnostics() []Diagnostic {
+	return d.diagnostics
+}
+
+// diagnose adds a diagnostic for the error.
+func (d *Decoder) diagnose(err error, severity Severity) {
+	diagnostic := Diagnostic{Severity: severity, Message: err.Error()}
+	var pe *buffer.ParseError
+	var ue *buffer.UnsupportedSectionError
+	if errors.As(err, &pe) {
+		diagnostic.Line, diagnostic.Col = pe.Line, pe.Col
+		diagnostic.Section, diagnostic.Field = pe.Section, pe.Field
+		diagnostic.Message = pe.Err.Error()
+	} else if errors.As(err, &ue) {
+		diagnostic.Line, diagnostic.Col = ue.Line, ue.Col
+		diagnostic.Message = fmt.Sprintf("unsupported section %q: records skipped", ue.Header)
+	}
+	d.diagnostics = append(d.diagnostics, diagnostic)
+}
+
+// header starts a new section.
+func (d *Decoder) header(buf buffer.Buffer) error {
+	if d.accountList.reading {
+		d.accountList.reading, d.accountList.done = false, true
+	}
+
+	header := strings.TrimSpace(string(buf.Buffer[1:]))
+	if header == "Clear:AutoSwitch" || header == "Option:AutoSwitch" {
+		// ignore
+		return nil
+	}
+	d.section.header, d.section.name, d.section.skipped = header, "", false
+	d.active.record = nil
+	d.section.line, d.section.col = buf.Line, buf.Col
+
+	switch header {
+	case "Account":
+		d.section.name = "accounts"
+	case "Type:Cat":
+		d.section.name = "categories"
+	case "Type:Class":
+		d.section.name = "classes"
+	case "Type:Security":
+		d.section.name = "securities"
+	case "Type:Tag":
+		d.section.name = "tags"
+	case "Type:Invst":
+		d.section.name = "investments"
+	case "Type:Bank", "Type:Cash", "Type:CCard", "Type:Oth A", "Type:Oth L", "Type:Memorized", "Type:Prices":
+		d.section.name = "transactions"
+	default:
+		d.section.skipped = true
+		return &buffer.UnsupportedSectionError{Line: buf.Line, Col: buf.Col, Header: header}
+	}
+
+	return nil
+}
+
+// record reads a single record from the chunk.
+// It returns nil if the record only sets the context for later records.
+func (d *Decoder) record(buf buffer.Buffer) (interface{}, error) {
+	if d.section.skipped {
+		return nil, nil
+	}
+
+	var record interface{}
+	var err error
+	switch d.section.header {
+	case "":
+		return nil, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Err: buffer.ErrUnexpectedInput}
+	case "Account":
+		var acct *account.Record
+		if acct, buf, err = account.ReadRecord(buf); acct != nil {
+			if d.accountList.done {
+				if prev := d.active.record; prev != nil && d.recover {
+					// only the last account of the section applies to the transactions that follow
+					d.diagnostics = append(d.diagnostics, Diagnostic{
+						Line:     prev.Line,
+						Col:      prev.Col,
+						Section:  d.section.name,
+						Severity: SeverityWarning,
+						Message:  fmt.Sprintf("account %q ignored: only the last account of a section after the account list is used", prev.Name),
+					})
+				}
+				d.active.account, d.active.accountType, d.active.record = acct.Name, acct.Type, acct
+			} else {
+				d.accountList.reading = true
+				record = acct
+			}
+		}
+	case "Type:Cat":
+		var cat *category.Record
+		if cat, buf, err = category.ReadRecord(buf); cat != nil {
+			record = cat
+		}
+	case "Type:Class":
+		var cls *class.Record
+		if cls, buf, err = class.ReadRecord(buf); cls != nil {
+			record = cls
+		}
+	case "Type:Security":
+		var sec *security.Record
+		if sec, buf, err = security.ReadRecord(buf); sec != nil {
+			record = sec
+		}
+	case "Type:Tag":
+		var tg *tag.Record
+		if tg, buf, err = tag.ReadRecord(buf); tg != nil {
+			record = tg
+		}
+	case "Type:Invst":
+		var xact *investment.Record
+		if xact, buf, err = investment.ReadRecord(buf, d.active.account); xact != nil {
+			record = xact
+		}
+	case "Type:Memorized", "Type:Prices":
+		var xact *transaction.Record
+		if xact, buf, err = transaction.ReadRecord(buf, "", strings.TrimPrefix(d.section.header, "Type:")); xact != nil {
+			record = xact
+		}
+	default:
+		var xact *transaction.Record
+		if xact, buf, err = transaction.ReadRecord(buf, d.active.account, strings.TrimPrefix(d.section.header, "Type:")); xact != nil {
+			record = xact
+		}
+	}
+	if err != nil {
+		return nil, err
+	} else if (record == nil && d.section.header != "Account") || len(buf.Buffer) != 0 {
+		return nil, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Err: buffer.ErrUnexpectedInput}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,64 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package reader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity is how serious a diagnostic is.
+type Severity string
+
+const (
+	// SeverityWarning means the input was accepted, but something was ignored.
+	SeverityWarning Severity = "warning"
+	// SeverityError means a record was dropped.
+	SeverityError Severity = "error"
+)
+
+// Diagnostic is a problem found while reading the input in recovery mode.
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Col      int      `json:"col"`
+	Section  string   `json:"section,omitempty"`
+	Field    string   `json:"field,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// String returns the diagnostic formatted as "line:col: severity: section: field: message".
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d:%d: %s: ", d.Line, d.Col, d.Severity))
+	if d.Section != "" {
+		sb.WriteString(d.Section + ": ")
+	}
+	if d.Field != "" {
+		sb.WriteString(d.Field + ": ")
+	}
+	sb.WriteString(d.Message)
+	return sb.String()
+}
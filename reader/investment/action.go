@@ -0,0 +1,164 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package investment
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action is the investment action from the N field.
+type Action string
+
+// The actions written by Quicken.
+// An action ending in X transfers cash to or from another account.
+const (
+	Buy       Action = "Buy"
+	BuyX      Action = "BuyX"
+	Sell      Action = "Sell"
+	SellX     Action = "SellX"
+	ShtSell   Action = "ShtSell"
+	CvrShrt   Action = "CvrShrt"
+	Div       Action = "Div"
+	DivX      Action = "DivX"
+	IntInc    Action = "IntInc"
+	IntIncX   Action = "IntIncX"
+	CGLong    Action = "CGLong"
+	CGLongX   Action = "CGLongX"
+	CGMid     Action = "CGMid"
+	CGMidX    Action = "CGMidX"
+	CGShort   Action = "CGShort"
+	CGShortX  Action = "CGShortX"
+	ReinvDiv  Action = "ReinvDiv"
+	ReinvInt  Action = "ReinvInt"
+	ReinvLg   Action = "ReinvLg"
+	ReinvMd   Action = "ReinvMd"
+	ReinvSh   Action = "ReinvSh"
+	RtrnCap   Action = "RtrnCap"
+	RtrnCapX  Action = "RtrnCapX"
+	ShrsIn    Action = "ShrsIn"
+	ShrsOut   Action = "ShrsOut"
+	StkSplit  Action = "StkSplit"
+	MiscExp   Action = "MiscExp"
+	MiscExpX  Action = "MiscExpX"
+	MiscInc   Action = "MiscInc"
+	MiscIncX  Action = "MiscIncX"
+	MargInt   Action = "MargInt"
+	MargIntX  Action = "MargIntX"
+	XIn       Action = "XIn"
+	XOut      Action = "XOut"
+	ContribX  Action = "ContribX"
+	WithdrwX  Action = "WithdrwX"
+	Cash      Action = "Cash"
+	Reminder  Action = "Reminder"
+	Grant     Action = "Grant"
+	Vest      Action = "Vest"
+	Exercise  Action = "Exercise"
+	ExercisX  Action = "ExercisX"
+	Expire    Action = "Expire"
+	ShrsInX   Action = "ShrsInX"
+	ShrsOutX  Action = "ShrsOutX"
+	ReinvCash Action = "ReinvCash"
+)
+
+// requires lists the fields each action must have.
+type requires struct {
+	security bool // Y
+	shares   bool // Q
+	amount   bool // T
+	transfer bool // L[account]
+}
+
+var rules = map[Action]requires{
+	Buy:       {security: true, shares: true},
+	BuyX:      {security: true, shares: true, transfer: true},
+	Sell:      {security: true, shares: true},
+	SellX:     {security: true, shares: true, transfer: true},
+	ShtSell:   {security: true, shares: true},
+	CvrShrt:   {security: true, shares: true},
+	Div:       {security: true, amount: true},
+	DivX:      {security: true, amount: true, transfer: true},
+	IntInc:    {amount: true},
+	IntIncX:   {amount: true, transfer: true},
+	CGLong:    {security: true, amount: true},
+	CGLongX:   {security: true, amount: true, transfer: true},
+	CGMid:     {security: true, amount: true},
+	CGMidX:    {security: true, amount: true, transfer: true},
+	CGShort:   {security: true, amount: true},
+	CGShortX:  {security: true, amount: true, transfer: true},
+	ReinvDiv:  {security: true, shares: true},
+	ReinvInt:  {security: true, shares: true},
+	ReinvLg:   {security: true, shares: true},
+	ReinvMd:   {security: true, shares: true},
+	ReinvSh:   {security: true, shares: true},
+	ReinvCash: {security: true, shares: true},
+	RtrnCap:   {security: true, amount: true},
+	RtrnCapX:  {security: true, amount: true, transfer: true},
+	ShrsIn:    {security: true, shares: true},
+	ShrsInX:   {security: true, shares: true, transfer: true},
+	ShrsOut:   {security: true, shares: true},
+	ShrsOutX:  {security: true, shares: true, transfer: true},
+	StkSplit:  {security: true, shares: true},
+	MiscExp:   {amount: true},
+	MiscExpX:  {amount: true, transfer: true},
+	MiscInc:   {amount: true},
+	MiscIncX:  {amount: true, transfer: true},
+	MargInt:   {amount: true},
+	MargIntX:  {amount: true, transfer: true},
+	XIn:       {amount: true, transfer: true},
+	XOut:      {amount: true, transfer: true},
+	ContribX:  {amount: true, transfer: true},
+	WithdrwX:  {amount: true, transfer: true},
+	Cash:      {amount: true},
+	Reminder:  {},
+	Grant:     {security: true},
+	Vest:      {security: true},
+	Exercise:  {security: true},
+	ExercisX:  {security: true, transfer: true},
+	Expire:    {security: true},
+}
+
+// actions maps the lower-case name of each action to the action.
+var actions = func() map[string]Action {
+	m := make(map[string]Action, len(rules))
+	for action := range rules {
+		m[strings.ToLower(string(action))] = action
+	}
+	return m
+}()
+
+// ParseAction returns the action for the text of an N field.
+// The comparison ignores case, since Quicken isn't consistent about it.
+func ParseAction(text string) (Action, error) {
+	if action, ok := actions[strings.ToLower(strings.TrimSpace(text))]; ok {
+		return action, nil
+	}
+	return "", fmt.Errorf("unknown action %q", text)
+}
+
+// IsTransfer returns true if the action moves cash to or from another account.
+func (a Action) IsTransfer() bool {
+	return rules[a].transfer
+}
@@ -0,0 +1,248 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+// Package investment reads the records of a !Type:Invst section.
+package investment
+
+import (
+	"github.com/mdhender/qif2json/buffer"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/class"
+	"strings"
+)
+
+type Section struct {
+	Line    int       `json:"-"`
+	Col     int       `json:"-"`
+	Records []*Record `json:"records,omitempty"`
+}
+
+type Record struct {
+	Line            int `json:"-"`
+	Col             int `json:"-"`
+	Account         string
+	Action          Action
+	Amount          decimal.Decimal // T, the total amount of the transaction
+	AmountUCode     decimal.Decimal
+	Category        string // L, if it is not a transfer, without the class
+	Class           string // the text after the '/' in L
+	ClearedStatus   string
+	Commission      decimal.Decimal
+	Date            string
+	Memo            string
+	Payee           string // P, the text of the transaction
+	OriginalPayee   string // the payee before it was normalized, if it was
+	Price           decimal.Decimal
+	Security        string          // Y, the name of the security
+	Shares          decimal.Decimal // Q, or the split ratio for StkSplit
+	TransferAccount string          // if L is [xxxx], then TransferAccount is 'xxxx'
+	TransferAmount  decimal.Decimal // $, the amount transferred
+}
+
+func ReadSection(buf buffer.Buffer, account string) (*Section, buffer.Buffer, error) {
+	saved, sname, section := buf, "investments", Section{Line: buf.Line, Col: buf.Col}
+
+	lit, bb := buf.Literal("!Type:Invst")
+	if lit == nil {
+		return nil, saved, nil
+	}
+	buf = bb
+
+	// read the section detail
+	var err error
+	for {
+		var record *Record
+		record, buf, err = ReadRecord(buf, account)
+		if err != nil {
+			return nil, buf, err
+		} else if record == nil {
+			break
+		}
+		section.Records = append(section.Records, record)
+	}
+
+	// read the end of section marker
+	eos, bb := buf.EndOfSection()
+	if eos == nil {
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrUnexpectedInput}
+	}
+	buf = bb
+
+	return &section, buf, nil
+}
+
+func ReadRecord(buf buffer.Buffer, account string) (*Record, buffer.Buffer, error) {
+	saved, sname, record := buf, "investment", Record{Line: buf.Line, Col: buf.Col, Account: account}
+
+	var found bool
+	var action, category, cleared, date, memo, payee, security, toAccount []byte
+	var commission, price, shares, tcode, transfer, ucode *decimal.Decimal
+	for {
+		if action == nil {
+			if action, buf = buf.Field("N"); action != nil {
+				var err error
+				if record.Action, err = ParseAction(string(action)); err != nil {
+					return nil, saved, buf.FieldError(err, sname, "action")
+				}
+				found = true
+				continue
+			}
+		}
+		if cleared == nil {
+			if cleared, buf = buf.Field("C"); cleared != nil {
+				found, record.ClearedStatus = true, string(cleared)
+				continue
+			}
+		}
+		if commission == nil {
+			var err error
+			if commission, buf, err = buf.Amount("O"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "commission")
+			} else if commission != nil {
+				found, record.Commission = true, *commission
+				continue
+			}
+		}
+		if date == nil {
+			var err error
+			if date, buf, err = buf.Date("D"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "date")
+			} else if date != nil {
+				found, record.Date = true, string(date)
+				continue
+			}
+		}
+		if memo == nil {
+			if memo, buf = buf.Field("M"); memo != nil {
+				found, record.Memo = true, string(memo)
+				continue
+			}
+		}
+		if payee == nil {
+			if payee, buf = buf.Field("P"); payee != nil {
+				found, record.Payee = true, string(payee)
+				continue
+			}
+		}
+		if price == nil {
+			var err error
+			if price, buf, err = buf.Amount("I"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "price")
+			} else if price != nil {
+				found, record.Price = true, *price
+				continue
+			}
+		}
+		if shares == nil {
+			var err error
+			if shares, buf, err = buf.Amount("Q"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "shares")
+			} else if shares != nil {
+				found, record.Shares = true, *shares
+				continue
+			}
+		}
+		if security == nil {
+			if security, buf = buf.Field("Y"); security != nil {
+				found, record.Security = true, string(security)
+				continue
+			}
+		}
+		if tcode == nil {
+			var err error
+			if tcode, buf, err = buf.Amount("T"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "amount")
+			} else if tcode != nil {
+				found, record.Amount = true, *tcode
+				continue
+			}
+		}
+		if transfer == nil {
+			var err error
+			if transfer, buf, err = buf.Amount("$"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "transfer amount")
+			} else if transfer != nil {
+				found, record.TransferAmount = true, *transfer
+				continue
+			}
+		}
+		if ucode == nil {
+			var err error
+			if ucode, buf, err = buf.Amount("U"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "amount (U)")
+			} else if ucode != nil {
+				found, record.AmountUCode = true, *ucode
+				continue
+			}
+		}
+
+		// category must follow toAccount since they share a common prefix
+		if toAccount == nil {
+			if toAccount, buf = buf.Field("L["); toAccount != nil {
+				var transfer string
+				transfer, record.Class = class.Cut(string(toAccount))
+				found, record.TransferAccount = true, strings.TrimRight(transfer, "]")
+				continue
+			}
+		}
+		if category == nil {
+			if category, buf = buf.Field("L"); category != nil {
+				found = true
+				record.Category, record.Class = class.Cut(string(category))
+				continue
+			}
+		}
+
+		break
+	}
+
+	if !found { // no fields found
+		return nil, saved, nil
+	}
+
+	// check for required fields
+	if date == nil {
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "date", Err: buffer.ErrMissingField}
+	} else if action == nil {
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "action", Err: buffer.ErrMissingField}
+	}
+	rule := rules[record.Action]
+	if rule.security && security == nil {
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "security", Err: buffer.ErrMissingField}
+	} else if rule.shares && shares == nil {
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "shares", Err: buffer.ErrMissingField}
+	} else if rule.amount && tcode == nil && ucode == nil {
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "amount", Err: buffer.ErrMissingField}
+	} else if rule.transfer && record.TransferAccount == "" {
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "transfer account", Err: buffer.ErrMissingField}
+	}
+
+	eor, bb := buf.EndOfRecord()
+	if eor == nil {
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrMissingTerminator}
+	}
+	buf = bb
+
+	return &record, buf, nil
+}
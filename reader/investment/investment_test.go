@@ -0,0 +1,81 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package investment
+
+import (
+	"errors"
+	"github.com/mdhender/qif2json/buffer"
+	"testing"
+)
+
+func TestParseAction(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want Action
+	}{
+		{"BuyX", BuyX},
+		{"buyx", BuyX},
+		{" SELL ", Sell},
+		{"ReinvCash", ReinvCash},
+		{"cglongx", CGLongX},
+	} {
+		if got, err := ParseAction(tc.text); err != nil || got != tc.want {
+			t.Errorf("ParseAction(%q) = %q, %v, want %q", tc.text, got, err, tc.want)
+		}
+	}
+	if _, err := ParseAction("Buy X"); err == nil {
+		t.Errorf("ParseAction(%q): want an error", "Buy X")
+	}
+}
+
+func TestReadRecordRequiredFields(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		field string // the missing field, if any
+	}{
+		{"transfer account", "D1/1/2021\nNXOut\nT100.00\nL[Checking]\n^\n", ""},
+		{"transfer amount without account", "D1/1/2021\nNXOut\nT100.00\n$100.00\n^\n", "transfer account"},
+		{"empty transfer account", "D1/1/2021\nNXOut\nT100.00\nL[]\n^\n", "transfer account"},
+		{"no transfer", "D1/1/2021\nNXOut\nT100.00\n^\n", "transfer account"},
+		{"buy", "D1/1/2021\nNBuy\nYACME\nQ10\nT100.00\n^\n", ""},
+		{"buy without shares", "D1/1/2021\nNBuy\nYACME\nT100.00\n^\n", "shares"},
+		{"dividend without security", "D1/1/2021\nNDiv\nT5.00\n^\n", "security"},
+		{"no action", "D1/1/2021\nT5.00\n^\n", "action"},
+	} {
+		buf := buffer.Buffer{Line: 2, Col: 1, Buffer: []byte(tc.input)}
+		record, _, err := ReadRecord(buf, "Brokerage")
+		if tc.field == "" {
+			if err != nil || record == nil {
+				t.Errorf("%s: ReadRecord = %v, %v, want a record", tc.name, record, err)
+			}
+			continue
+		}
+		var pe *buffer.ParseError
+		if !errors.As(err, &pe) || !errors.Is(err, buffer.ErrMissingField) || pe.Field != tc.field {
+			t.Errorf("%s: ReadRecord: %v, want missing field %s", tc.name, err, tc.field)
+		}
+	}
+}
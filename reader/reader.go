@@ -25,127 +25,91 @@
 package reader
 
 import (
-	"fmt"
+	"bytes"
 	"github.com/mdhender/qif2json/buffer"
 	"github.com/mdhender/qif2json/reader/account"
 	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/class"
+	"github.com/mdhender/qif2json/reader/investment"
 	"github.com/mdhender/qif2json/reader/security"
 	"github.com/mdhender/qif2json/reader/tag"
 	"github.com/mdhender/qif2json/reader/transaction"
+	"io"
 )
 
 type Reader struct {
-	active struct {
-		account     string
-		accountType string
-	}
 	Accounts     *account.Section      `json:"accounts,omitempty"`
 	Categories   *category.Section     `json:"categories,omitempty"`
+	Classes      *class.Section        `json:"classes,omitempty"`
 	Securities   *security.Section     `json:"securities,omitempty"`
 	Tags         *tag.Section          `json:"tags,omitempty"`
 	Transactions []*transaction.Record `json:"transactions,omitempty"`
+	Investments  []*investment.Record  `json:"investments,omitempty"`
 	Memorized    []*transaction.Record `json:"-"`
 	Prices       []*transaction.Record `json:"-"`
+	Diagnostics  []Diagnostic          `json:"diagnostics,omitempty"`
 }
 
+// Read reads all of the records from the buffer.
 func Read(buf buffer.Buffer) (*Reader, error) {
+	return ReadFrom(bytes.NewReader(buf.Buffer), Options{Dates: buf.Dates, Numbers: buf.Numbers})
+}
+
+// ReadFrom reads all of the records from the input.
+// The input is parsed one record at a time, but every record is kept,
+// so memory use grows with the size of the input. Use a Decoder to handle
+// the records one at a time without keeping them.
+// In recovery mode, the problems found are returned in Diagnostics.
+func ReadFrom(input io.Reader, opts Options) (*Reader, error) {
 	var r Reader
-	for len(buf.Buffer) != 0 {
-		if literal, bb := buf.Literal("!Clear:AutoSwitch"); literal != nil {
-			// ignore
-			buf = bb
-			continue
-		}
-		if literal, bb := buf.Literal("!Option:AutoSwitch"); literal != nil {
-			// ignore
-			buf = bb
-			continue
-		}
-		if section, bb, err := account.ReadSection(buf); err != nil {
+	d := NewDecoder(input, opts)
+	for {
+		record, err := d.Next()
+		if err == io.EOF {
+			r.Diagnostics = d.Diagnostics()
+			break
+		} else if err != nil {
 			return nil, err
-		} else if section != nil {
-			if len(section.Records) != 0 {
-				if r.Accounts == nil {
-					r.Accounts = section
-				} else if len(section.Records) == 1 {
-					r.active.account = section.Records[0].Name
-					r.active.accountType = section.Records[0].Type
-				} else {
-					panic("!")
-				}
-			}
-			buf = bb
-			continue
 		}
-		if section, bb, err := category.ReadSection(buf); err != nil {
-			return nil, err
-		} else if section != nil {
-			if len(section.Records) != 0 {
-				if r.Categories == nil {
-					r.Categories = section
-				} else {
-					panic("!")
-				}
+		_, line, col := d.Section()
+		switch record := record.(type) {
+		case *account.Record:
+			if r.Accounts == nil {
+				r.Accounts = &account.Section{Line: line, Col: col}
 			}
-			buf = bb
-			continue
-		}
-		if section, bb, err := security.ReadSection(buf); err != nil {
-			return nil, err
-		} else if section != nil {
-			if len(section.Records) != 0 {
-				if r.Securities == nil {
-					r.Securities = &security.Section{
-						Line: buf.Line,
-						Col:  buf.Col,
-					}
-				}
-				r.Securities.Records = append(r.Securities.Records, section.Records...)
+			r.Accounts.Records = append(r.Accounts.Records, record)
+		case *category.Record:
+			if r.Categories == nil {
+				r.Categories = &category.Section{Line: line, Col: col}
 			}
-			buf = bb
-			continue
-		}
-		if section, bb, err := tag.ReadSection(buf); err != nil {
-			return nil, err
-		} else if section != nil {
-			if len(section.Records) != 0 {
-				if r.Tags == nil {
-					r.Tags = section
-				} else {
-					panic("!")
-				}
+			r.Categories.Records = append(r.Categories.Records, record)
+		case *class.Record:
+			if r.Classes == nil {
+				r.Classes = &class.Section{Line: line, Col: col}
 			}
-			buf = bb
-			continue
-		}
-		if section, bb, err := transaction.ReadSection(buf, r.active.account, r.active.accountType); err != nil {
-			return nil, err
-		} else if section != nil {
-			for _, xact := range section.Records {
-				r.Transactions = append(r.Transactions, xact)
+			r.Classes.Records = append(r.Classes.Records, record)
+		case *security.Record:
+			if r.Securities == nil {
+				r.Securities = &security.Section{Line: line, Col: col}
 			}
-			buf = bb
-			continue
-		}
-		if section, bb, err := transaction.ReadSection(buf, "", "Memorized"); err != nil {
-			return nil, err
-		} else if section != nil {
-			for _, xact := range section.Records {
-				r.Memorized = append(r.Memorized, xact)
+			r.Securities.Records = append(r.Securities.Records, record)
+		case *tag.Record:
+			if r.Tags == nil {
+				r.Tags = &tag.Section{Line: line, Col: col}
 			}
-			buf = bb
-			continue
-		}
-		if section, bb, err := transaction.ReadSection(buf, "", "Prices"); err != nil {
-			return nil, err
-		} else if section != nil {
-			for _, xact := range section.Records {
-				r.Prices = append(r.Prices, xact)
+			r.Tags.Records = append(r.Tags.Records, record)
+		case *investment.Record:
+			r.Investments = append(r.Investments, record)
+		case *transaction.Record:
+			switch record.Type {
+			case "Memorized":
+				r.Memorized = append(r.Memorized, record)
+			case "Prices":
+				r.Prices = append(r.Prices, record)
+			default:
+				r.Transactions = append(r.Transactions, record)
 			}
-			buf = bb
-			continue
 		}
-		return nil, fmt.Errorf("%d:%d: unexpected input", buf.Line, buf.Col)
 	}
 	return &r, nil
 }
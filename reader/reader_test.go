@@ -0,0 +1,100 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package reader
+
+import (
+	"errors"
+	"github.com/mdhender/qif2json/buffer"
+	"strings"
+	"testing"
+)
+
+// malformed has a bad date, an invalid character, an unsupported
+// section and a bad amount between the good records.
+const malformed = "!Type:Bank\nD1/1/2021\nT1.00\n^\n" +
+	"D13/1/2021\nT2.00\n^\n" +
+	"D1/3/2021\nPcaf\xe9\nT3.00\n^\n" +
+	"!Type:Budget\nX\n^\n" +
+	"!Type:Cash\nD1/4/2021\nTabc\n^\n" +
+	"D1/5/2021\nT5.00\n^\n"
+
+func TestReadFromRecover(t *testing.T) {
+	r, err := ReadFrom(strings.NewReader(malformed), Options{Recover: true})
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if len(r.Transactions) != 2 {
+		t.Errorf("read %d transactions, want 2", len(r.Transactions))
+	} else if r.Transactions[0].Date != "2021/01/01" || r.Transactions[1].Date != "2021/01/05" {
+		t.Errorf("read transactions dated %s and %s, want 2021/01/01 and 2021/01/05", r.Transactions[0].Date, r.Transactions[1].Date)
+	}
+
+	want := []Diagnostic{
+		{Line: 5, Col: 2, Section: "transaction", Field: "date", Severity: SeverityError, Message: `invalid date "13/1/2021": month out of range`},
+		{Line: 9, Col: 4, Section: "transactions", Severity: SeverityError, Message: "invalid utf-8 character"},
+		{Line: 12, Col: 1, Severity: SeverityWarning, Message: `unsupported section "Type:Budget": records skipped`},
+		{Line: 17, Col: 2, Section: "transaction", Field: "amount", Severity: SeverityError, Message: `invalid amount "abc": unexpected character 'a'`},
+	}
+	if len(r.Diagnostics) != len(want) {
+		t.Fatalf("got %d diagnostics %v, want %d", len(r.Diagnostics), r.Diagnostics, len(want))
+	}
+	for i := range want {
+		if r.Diagnostics[i] != want[i] {
+			t.Errorf("diagnostic %d: got %q, want %q", i, r.Diagnostics[i], want[i])
+		}
+	}
+}
+
+func TestReadFromStopsAtFirstError(t *testing.T) {
+	r, err := ReadFrom(strings.NewReader(malformed), Options{})
+	var pe *buffer.ParseError
+	if r != nil || !errors.As(err, &pe) {
+		t.Fatalf("ReadFrom: got %v, want a ParseError", err)
+	}
+	if pe.Line != 5 || pe.Col != 2 || pe.Section != "transaction" || pe.Field != "date" {
+		t.Errorf("ReadFrom: got %v, want the bad date at 5:2", err)
+	}
+}
+
+func TestReadFromAccountBlock(t *testing.T) {
+	// the second !Account section has two records, so Checking is ignored
+	input := "!Account\nNChecking\nTBank\n^\nNSavings\nTBank\n^\n" +
+		"!Type:Cat\nNFood\nE\n^\n" +
+		"!Account\nNChecking\nTBank\n^\nNSavings\nTBank\n^\n" +
+		"!Type:Bank\nD1/1/2021\nT1.00\n^\n"
+	r, err := ReadFrom(strings.NewReader(input), Options{Recover: true})
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if len(r.Transactions) != 1 || r.Transactions[0].Account != "Savings" {
+		t.Errorf("read transactions %v, want one in Savings", r.Transactions)
+	}
+	want := []Diagnostic{
+		{Line: 13, Col: 1, Section: "accounts", Severity: SeverityWarning, Message: `account "Checking" ignored: only the last account of a section after the account list is used`},
+	}
+	if len(r.Diagnostics) != len(want) || r.Diagnostics[0] != want[0] {
+		t.Errorf("got diagnostics %q, want %q", r.Diagnostics, want)
+	}
+}
@@ -25,7 +25,6 @@
 package security
 
 import (
-	"fmt"
 	"github.com/mdhender/qif2json/buffer"
 )
 
@@ -60,7 +59,7 @@ func ReadSection(buf buffer.Buffer) (*Section, buffer.Buffer, error) {
 		var record *Record
 		record, buf, err = ReadRecord(buf)
 		if err != nil {
-			return nil, buf, fmt.Errorf("%d: %s: %w", section.Line, sname, err)
+			return nil, buf, err
 		} else if record == nil {
 			break
 		}
@@ -70,7 +69,7 @@ func ReadSection(buf buffer.Buffer) (*Section, buffer.Buffer, error) {
 	// read the end of section marker
 	eos, bb := buf.EndOfSection()
 	if eos == nil {
-		return nil, saved, fmt.Errorf("%d: %s: %d:%d: unexpected input", section.Line, sname, buf.Line, buf.Col)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrUnexpectedInput}
 	}
 	buf = bb
 
@@ -123,12 +122,12 @@ func ReadRecord(buf buffer.Buffer) (*Record, buffer.Buffer, error) {
 
 	// check for required fields
 	if name == nil {
-		return nil, saved, fmt.Errorf("%d: %s: missing field %q", record.Line, sname, "name")
+		return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "name", Err: buffer.ErrMissingField}
 	}
 
 	eor, bb := buf.EndOfRecord()
 	if eor == nil {
-		return nil, saved, fmt.Errorf("%d: %s: missing record terminator", buf.Line, sname)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrMissingTerminator}
 	}
 	buf = bb
 
@@ -25,9 +25,9 @@
 package transaction
 
 import (
-	"fmt"
 	"github.com/mdhender/qif2json/buffer"
-	"github.com/mdhender/qif2json/stdlib"
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/class"
 	"strings"
 )
 
@@ -42,19 +42,21 @@ type Record struct {
 	Col           int
 	Account       string
 	Address       []string // Up to five lines (the sixth line is an optional message)
-	AmountTCode   string
-	AmountUCode   string
-	BudgetAmount  []string
-	Category      string // Category/Subcategory/Transfer/Class
+	AmountTCode   decimal.Decimal
+	AmountUCode   decimal.Decimal
+	Amortization  *Amortization // the loan of a memorized transaction
+	Category      string        // Category:Subcategory, without the class
+	Class         string        // Class:Subclass, from the text after the '/' in the category
 	ClearedStatus string
-	Commission    string
+	Commission    decimal.Decimal
 	Date          string
-	Interest      string
+	Interest      decimal.Decimal
 	Memo          string
 	MemorizedFlag string
-	Quantity      string
+	Quantity      decimal.Decimal
 	Payee         string
-	Price         string
+	OriginalPayee string // the payee before it was normalized, if it was
+	Price         decimal.Decimal
 	RefNo         string // (check or reference number)
 	Split         []*Split
 	Ticker        string
@@ -62,13 +64,33 @@ type Record struct {
 	Type          string
 }
 
+// Amortization is the loan of a memorized loan payment, from the fields 1 to 7.
+type Amortization struct {
+	FirstPaymentDate string          // 1
+	Years            decimal.Decimal // 2, the length of the loan
+	PaymentsMade     decimal.Decimal // 3
+	PeriodsPerYear   decimal.Decimal // 4
+	InterestRate     decimal.Decimal // 5
+	CurrentBalance   decimal.Decimal // 6
+	OriginalAmount   decimal.Decimal // 7, the original loan amount
+}
+
 type Split struct {
-	Line     int    `json:"-"`
-	Col      int    `json:"-"`
-	Account  string `json:"account,omitempty"`
-	Amount   string `json:"amount,omitempty"`
-	Category string `json:"category,omitempty"`
-	Memo     string `json:"memo,omitempty"`
+	Line     int              `json:"-"`
+	Col      int              `json:"-"`
+	Account  string           `json:"account,omitempty"`
+	Amount   *decimal.Decimal `json:"amount,omitempty"` // nil if the split has no $ field
+	Category string           `json:"category,omitempty"`
+	Class    string           `json:"class,omitempty"`
+	Memo     string           `json:"memo,omitempty"`
+}
+
+// AmountOrZero returns the amount of the split, or zero if it has none.
+func (s *Split) AmountOrZero() decimal.Decimal {
+	if s.Amount == nil {
+		return decimal.Decimal{}
+	}
+	return *s.Amount
 }
 
 func ReadSection(buf buffer.Buffer, account, accountType string) (*Section, buffer.Buffer, error) {
@@ -79,7 +101,7 @@ func ReadSection(buf buffer.Buffer, account, accountType string) (*Section, buff
 	case "Bank", "Cash", "CCard", "Invst", "Oth A", "Oth L", "Memorized", "Prices":
 		literal = "!Type:" + accountType
 	default:
-		panic(fmt.Sprintf("assert(account.type != %q)", accountType))
+		return nil, saved, &buffer.UnsupportedSectionError{Line: buf.Line, Col: buf.Col, Header: "Type:" + accountType}
 	}
 	lit, bb := buf.Literal(literal)
 	if lit == nil {
@@ -93,7 +115,7 @@ func ReadSection(buf buffer.Buffer, account, accountType string) (*Section, buff
 		var record *Record
 		record, buf, err = ReadRecord(buf, account, accountType)
 		if err != nil {
-			return nil, buf, fmt.Errorf("%d: %s: %w", section.Line, sname, err)
+			return nil, buf, err
 		} else if record == nil {
 			break
 		}
@@ -103,8 +125,7 @@ func ReadSection(buf buffer.Buffer, account, accountType string) (*Section, buff
 	// read the end of section marker
 	eos, bb := buf.EndOfSection()
 	if eos == nil {
-		fmt.Printf("error here %q\n", string(buf.Buffer[:20]))
-		return nil, saved, fmt.Errorf("%d: %s: %d:%d: unexpected input", section.Line, sname, buf.Line, buf.Col)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrUnexpectedInput}
 	}
 	buf = bb
 
@@ -115,7 +136,8 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 	saved, sname, record := buf, "transaction", Record{Line: buf.Line, Col: buf.Col, Account: account, Type: accountType}
 
 	var found bool
-	var category, cleared, commission, date, interest, memo, memorized, payee, qty, refNo, ticker, tcode, toAccount, ucode []byte
+	var category, cleared, date, memo, memorized, payee, refNo, ticker, toAccount []byte
+	var commission, interest, qty, tcode, ucode *decimal.Decimal
 	var split *Split
 	for {
 		if addrLine, bb := buf.Field("A"); addrLine != nil {
@@ -130,20 +152,29 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 			}
 		}
 		if commission == nil {
-			if commission, buf = buf.Field("O"); commission != nil {
-				found, record.Commission = true, string(commission)
+			var err error
+			if commission, buf, err = buf.Amount("O"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "commission")
+			} else if commission != nil {
+				found, record.Commission = true, *commission
 				continue
 			}
 		}
 		if date == nil {
-			if date, buf = buf.Date("D"); date != nil {
+			var err error
+			if date, buf, err = buf.Date("D"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "date")
+			} else if date != nil {
 				found, record.Date = true, string(date)
 				continue
 			}
 		}
 		if interest == nil {
-			if interest, buf = buf.Field("I"); interest != nil {
-				found, record.Interest = true, string(interest)
+			var err error
+			if interest, buf, err = buf.Amount("I"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "interest")
+			} else if interest != nil {
+				found, record.Interest = true, *interest
 				continue
 			}
 		}
@@ -168,17 +199,27 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 		if price, bb := buf.Field("\""); price != nil {
 			lexeme := strings.TrimRight(string(price), "\"")
 			if fields := strings.Split(lexeme, "\""); len(fields) == 3 {
+				// the price is between the commas that separate it from the ticker and date
+				price := strings.TrimSuffix(strings.TrimPrefix(fields[1], ","), ",")
+				var err error
+				if record.Price, err = buf.ParseAmount([]byte(price)); err != nil {
+					return nil, saved, buf.FieldError(err, sname, "price")
+				}
 				found, record.Ticker = true, fields[0]
-				record.Price = strings.ReplaceAll(fields[1], ",", "")
-				date = []byte(fields[2])
-				record.Date = stdlib.Date(date)
+				if date, err = buf.ParseDate([]byte(fields[2])); err != nil {
+					return nil, saved, buf.FieldError(err, sname, "date")
+				}
+				record.Date = string(date)
 				buf = bb
 				continue
 			}
 		}
 		if qty == nil {
-			if qty, buf = buf.Field("Q"); qty != nil {
-				found, record.Quantity = true, string(qty)
+			var err error
+			if qty, buf, err = buf.Amount("Q"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "quantity")
+			} else if qty != nil {
+				found, record.Quantity = true, *qty
 				continue
 			}
 		}
@@ -188,19 +229,21 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 				continue
 			}
 		}
-		if splitAmount, bb := buf.Field("$"); splitAmount != nil {
+		if splitAmount, bb, err := buf.Amount("$"); err != nil {
+			return nil, saved, buf.FieldError(err, sname, "split amount")
+		} else if splitAmount != nil {
 			if split == nil {
 				split = &Split{Line: bb.Line}
 				record.Split = append(record.Split, split)
 			}
-			found, split.Amount = true, string(splitAmount)
+			found, split.Amount = true, splitAmount
 			buf = bb
 			continue
 		}
 		if splitCategory, bb := buf.Field("S"); splitCategory != nil {
 			split = &Split{Line: bb.Line}
 			found, record.Split = true, append(record.Split, split)
-			split.Category = string(splitCategory)
+			split.Category, split.Class = class.Cut(string(splitCategory))
 			if strings.HasPrefix(split.Category, "[") {
 				split.Account = strings.Trim(split.Category, "[]")
 				split.Category = ""
@@ -218,8 +261,11 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 			continue
 		}
 		if tcode == nil {
-			if tcode, buf = buf.Field("T"); tcode != nil {
-				found, record.AmountTCode = true, string(tcode)
+			var err error
+			if tcode, buf, err = buf.Amount("T"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "amount")
+			} else if tcode != nil {
+				found, record.AmountTCode = true, *tcode
 				continue
 			}
 		}
@@ -230,8 +276,11 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 			}
 		}
 		if ucode == nil {
-			if ucode, buf = buf.Field("U"); ucode != nil {
-				found, record.AmountUCode = true, string(ucode)
+			var err error
+			if ucode, buf, err = buf.Amount("U"); err != nil {
+				return nil, saved, buf.FieldError(err, sname, "amount (U)")
+			} else if ucode != nil {
+				found, record.AmountUCode = true, *ucode
 				continue
 			}
 		}
@@ -239,29 +288,51 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 		// category must follow toAccount since they share a common prefix
 		if toAccount == nil {
 			if toAccount, buf = buf.Field("L["); toAccount != nil {
-				found, record.ToAccount = true, strings.TrimRight(string(toAccount), "]")
+				var transfer string
+				transfer, record.Class = class.Cut(string(toAccount))
+				found, record.ToAccount = true, strings.TrimRight(transfer, "]")
 				continue
 			}
 		}
 		if category == nil {
 			if category, buf = buf.Field("L"); category != nil {
-				found, record.Category = true, string(category)
+				found = true
+				record.Category, record.Class = class.Cut(string(category))
 				continue
 			}
 		}
 
-		// add budget amount logic here
-		if record.Type == "Memorized" {
-			var budgetAmount []byte
-			for _, flag := range []string{"1", "2", "3", "4", "5", "6", "7"} {
-				if budgetAmount == nil {
-					budgetAmount, buf = buf.Field(flag)
-				}
+		// the amortization fields of a memorized loan payment
+		if record.Type == "Memorized" && len(buf.Buffer) != 0 && '1' <= buf.Buffer[0] && buf.Buffer[0] <= '7' {
+			if record.Amortization == nil {
+				record.Amortization = &Amortization{}
 			}
-			if budgetAmount != nil {
-				found, record.BudgetAmount = true, append(record.BudgetAmount, string(budgetAmount))
-				continue
+			a := record.Amortization
+			if flag := string(buf.Buffer[:1]); flag == "1" {
+				firstPayment, bb, err := buf.Date(flag)
+				if err != nil {
+					return nil, saved, buf.FieldError(err, sname, "first payment date")
+				}
+				found, a.FirstPaymentDate, buf = true, string(firstPayment), bb
+			} else {
+				field := map[string]struct {
+					name  string
+					value *decimal.Decimal
+				}{
+					"2": {"years", &a.Years},
+					"3": {"payments made", &a.PaymentsMade},
+					"4": {"periods per year", &a.PeriodsPerYear},
+					"5": {"interest rate", &a.InterestRate},
+					"6": {"current balance", &a.CurrentBalance},
+					"7": {"original amount", &a.OriginalAmount},
+				}[flag]
+				amount, bb, err := buf.Amount(flag)
+				if err != nil {
+					return nil, saved, buf.FieldError(err, sname, field.name)
+				}
+				found, *field.value, buf = true, *amount, bb
 			}
+			continue
 		}
 
 		break
@@ -275,17 +346,17 @@ func ReadRecord(buf buffer.Buffer, account, accountType string) (*Record, buffer
 	switch record.Type {
 	case "Memorized":
 		if memorized == nil {
-			return nil, saved, fmt.Errorf("%d: %s: missing field %q", record.Line, sname, "memorized")
+			return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "memorized", Err: buffer.ErrMissingField}
 		}
 	default:
 		if date == nil {
-			return nil, saved, fmt.Errorf("%d: %s: missing field %q", record.Line, sname, "date")
+			return nil, saved, &buffer.ParseError{Line: record.Line, Col: record.Col, Section: sname, Field: "date", Err: buffer.ErrMissingField}
 		}
 	}
 
 	eor, bb := buf.EndOfRecord()
 	if eor == nil {
-		return nil, saved, fmt.Errorf("%d: %s: missing record terminator", buf.Line, sname)
+		return nil, saved, &buffer.ParseError{Line: buf.Line, Col: buf.Col, Section: sname, Err: buffer.ErrMissingTerminator}
 	}
 	buf = bb
 
@@ -24,32 +24,20 @@
 
 package stdlib
 
-import "fmt"
+import "github.com/mdhender/qif2json/date"
 
-// Date translates QIF date to a string with the date formatted as yyyy/mm/dd
-// The QIF date is formatted as mm/dd'yy. The month can be one or two digits
-// (eg, January is `1` while October is `10`). The day must be two characters,
-// but the first character may be a space instead of a zero. For example,
-// `01` and ` 1` are both the first day of the month. The year must be two
-// digits, and we're assuming it is always in the 21st century (eg, `16` is
-// converted to 2016, not 1916).
+// Date translates QIF date to a string with the date formatted as yyyy/mm/dd.
+// The date is parsed with date.Default, which accepts Quicken (12/31'98),
+// European (31.12.1998) and ISO (1998-12-31) dates. Two-digit years are
+// placed in the 20th or 21st century using date.DefaultPivot.
+// An invalid date is returned as "****/**/**".
 func Date(b []byte) string {
-	// 9/ 3'16 -> 2016/09/03
-	// 9/13'16 -> 2016/09/13
-	if len(b) == 7 && b[1] == '/' && b[4] == '\'' {
-		mm, dd, yy := b[0:1], b[2:4], b[5:]
-		return fmt.Sprintf("%4d/%02d/%02d", ToInt(yy)+2000, ToInt(mm), ToInt(dd))
+	yyyymmdd, err := date.Default.Parse(string(b))
+	if err != nil {
+		// invalid date
+		return "****/**/**"
 	}
-
-	// 12/ 9'16 -> 2016/12/09
-	// 12/19'16 -> 2016/12/19
-	if len(b) == 8 && b[2] == '/' && b[5] == '\'' {
-		mm, dd, yy := b[0:2], b[3:6], b[6:]
-		return fmt.Sprintf("%4d/%02d/%02d", ToInt(yy)+2000, ToInt(mm), ToInt(dd))
-	}
-
-	// invalid date
-	return "****/**/**"
+	return yyyymmdd
 }
 
 // Dup returns an exact copy of a slice.
@@ -0,0 +1,171 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/account"
+	"sort"
+)
+
+// ClearedState groups the QIF cleared statuses.
+type ClearedState string
+
+const (
+	Uncleared  ClearedState = "uncleared"
+	Cleared    ClearedState = "cleared"    // the * and c statuses
+	Reconciled ClearedState = "reconciled" // the X and R statuses
+)
+
+// ClearedStateOf returns the group of a QIF cleared status.
+func ClearedStateOf(status string) ClearedState {
+	switch status {
+	case "*", "c", "C":
+		return Cleared
+	case "X", "x", "R", "r":
+		return Reconciled
+	}
+	return Uncleared
+}
+
+// Breakdown is an amount split by cleared state.
+type Breakdown struct {
+	Cleared    decimal.Decimal
+	Reconciled decimal.Decimal
+	Uncleared  decimal.Decimal
+}
+
+func (b *Breakdown) add(state ClearedState, amount decimal.Decimal) {
+	switch state {
+	case Cleared:
+		b.Cleared = b.Cleared.Add(amount)
+	case Reconciled:
+		b.Reconciled = b.Reconciled.Add(amount)
+	default:
+		b.Uncleared = b.Uncleared.Add(amount)
+	}
+}
+
+// Balance is the balance of an account after a transaction.
+type Balance struct {
+	Transaction *Transaction
+	Amount      decimal.Decimal // the change to the account, the total of the splits
+	Balance     decimal.Decimal // the balance after the transaction
+}
+
+// AccountBalance is the running balance of an account.
+type AccountBalance struct {
+	Account   string
+	Type      string     // the Quicken account type, if the account is in the account list
+	Balances  []*Balance // in date order
+	Balance   decimal.Decimal
+	Breakdown Breakdown  // of the balance
+	Statement *Statement // nil if the account doesn't have a statement balance
+}
+
+// Statement compares the statement balance of an account to the balance
+// computed from the transactions on the statement date.
+//
+// The statement should agree with the transactions that have cleared, so
+// both the balance and the cleared balance are compared to it.
+type Statement struct {
+	Date               string // the statement date, empty if there isn't one
+	Balance            decimal.Decimal
+	Computed           decimal.Decimal // the balance after the transactions on or before the date
+	Breakdown          Breakdown       // of the computed balance
+	Discrepancy        decimal.Decimal // the statement balance less the computed balance
+	ClearedDiscrepancy decimal.Decimal // the statement balance less the cleared and reconciled balance
+}
+
+// Balances returns the running balance of each account, in the order the
+// accounts are first seen in the account list or the transactions.
+//
+// The transactions of each account are ordered by date, keeping the order
+// they were read for transactions on the same date. If the account has a
+// statement balance, it is compared to the balance on the statement date.
+// A statement without a date is compared to the ending balance. If more
+// than one record for an account has a statement balance, the last is used.
+//
+// Only bank transactions are included, so the balance of an investment
+// account isn't computed.
+func Balances(accounts *account.Section, transactions []*Transaction) []*AccountBalance {
+	var balances []*AccountBalance
+	byName := make(map[string]*AccountBalance)
+	find := func(name string) *AccountBalance {
+		if a, ok := byName[name]; ok {
+			return a
+		}
+		byName[name] = &AccountBalance{Account: name}
+		balances = append(balances, byName[name])
+		return byName[name]
+	}
+
+	statements := make(map[string]*account.Record)
+	if accounts != nil {
+		for _, record := range accounts.Records {
+			a := find(record.Name)
+			if record.Type != "" {
+				a.Type = record.Type
+			}
+			if record.StatementBalanceDate != "" || !record.StatementBalance.IsZero() {
+				statements[record.Name] = record
+			}
+		}
+	}
+
+	for _, t := range transactions {
+		a := find(t.Account)
+		b := Balance{Transaction: t}
+		for _, split := range t.Split {
+			b.Amount = b.Amount.Add(split.Amount)
+		}
+		a.Balances = append(a.Balances, &b)
+	}
+
+	for _, a := range balances {
+		sort.SliceStable(a.Balances, func(i, j int) bool {
+			return a.Balances[i].Transaction.Date < a.Balances[j].Transaction.Date
+		})
+		record, ok := statements[a.Account]
+		if ok {
+			a.Statement = &Statement{Date: record.StatementBalanceDate, Balance: record.StatementBalance}
+		}
+		for _, b := range a.Balances {
+			a.Balance = a.Balance.Add(b.Amount)
+			b.Balance = a.Balance
+			a.Breakdown.add(ClearedStateOf(b.Transaction.ClearedStatus), b.Amount)
+			if a.Statement != nil && (a.Statement.Date == "" || b.Transaction.Date <= a.Statement.Date) {
+				a.Statement.Computed = b.Balance
+				a.Statement.Breakdown = a.Breakdown
+			}
+		}
+		if s := a.Statement; s != nil {
+			s.Discrepancy = s.Balance.Sub(s.Computed)
+			s.ClearedDiscrepancy = s.Balance.Sub(s.Breakdown.Cleared.Add(s.Breakdown.Reconciled))
+		}
+	}
+
+	return balances
+}
@@ -0,0 +1,107 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/account"
+	"testing"
+)
+
+func TestBalances(t *testing.T) {
+	accounts := &account.Section{Records: []*account.Record{
+		{Name: "Checking", Type: "Bank", StatementBalance: decimal.MustParse("90.00"), StatementBalanceDate: "2021/01/31"},
+		{Name: "Savings", Type: "Bank", StatementBalance: decimal.MustParse("500.00")},
+		{Name: "Visa", Type: "CCard"},
+	}}
+	xact := func(account, date, status, amount string) *Transaction {
+		return &Transaction{Account: account, Date: date, ClearedStatus: status, Split: []*Split{{Amount: decimal.MustParse(amount)}}}
+	}
+	transactions := []*Transaction{
+		xact("Checking", "2021/02/01", "", "-1000.00"), // after the statement date
+		xact("Checking", "2021/01/31", "*", "-10.00"),  // on the statement date
+		xact("Checking", "2021/01/01", "X", "100.00"),
+		xact("Checking", "2021/01/31", "", "-5.00"), // on the date, but not cleared
+		xact("Savings", "2021/01/01", "R", "400.00"),
+		xact("Cash", "2021/01/01", "", "-20.00"),
+	}
+	balances := Balances(accounts, transactions)
+
+	var names []string
+	for _, a := range balances {
+		names = append(names, a.Account)
+	}
+	if len(balances) != 4 || names[0] != "Checking" || names[1] != "Savings" || names[2] != "Visa" || names[3] != "Cash" {
+		t.Fatalf("got accounts %q, want Checking, Savings, Visa and Cash", names)
+	}
+
+	checking := balances[0]
+	var running []string
+	for _, b := range checking.Balances {
+		running = append(running, b.Transaction.Date+" "+b.Balance.String())
+	}
+	// transactions on the same date keep the order they were read
+	want := []string{"2021/01/01 100.00", "2021/01/31 90.00", "2021/01/31 85.00", "2021/02/01 -915.00"}
+	if len(running) != len(want) {
+		t.Fatalf("checking: got balances %q, want %q", running, want)
+	}
+	for i := range want {
+		if running[i] != want[i] {
+			t.Errorf("checking: balance %d: got %q, want %q", i, running[i], want[i])
+		}
+	}
+
+	s := checking.Statement
+	if s == nil {
+		t.Fatal("checking: no statement")
+	}
+	for _, c := range []struct {
+		name      string
+		got, want decimal.Decimal
+	}{
+		{"computed", s.Computed, decimal.MustParse("85.00")},
+		{"discrepancy", s.Discrepancy, decimal.MustParse("5.00")},
+		{"cleared discrepancy", s.ClearedDiscrepancy, decimal.MustParse("0")},
+		{"cleared", s.Breakdown.Cleared, decimal.MustParse("-10.00")},
+		{"reconciled", s.Breakdown.Reconciled, decimal.MustParse("100.00")},
+		{"uncleared", s.Breakdown.Uncleared, decimal.MustParse("-5.00")},
+		{"balance", checking.Balance, decimal.MustParse("-915.00")},
+	} {
+		if !c.got.Equal(c.want) {
+			t.Errorf("checking: %s: got %s, want %s", c.name, c.got, c.want)
+		}
+	}
+
+	// a statement without a date is compared to the ending balance
+	if s := balances[1].Statement; s == nil || !s.Computed.Equal(decimal.MustParse("400.00")) || !s.Discrepancy.Equal(decimal.MustParse("100.00")) {
+		t.Errorf("savings: got statement %+v, want 400.00 computed and 100.00 discrepancy", s)
+	}
+	if balances[2].Statement != nil || balances[3].Statement != nil {
+		t.Errorf("got statements for accounts without a statement balance")
+	}
+	if balances[3].Type != "" || balances[2].Type != "CCard" {
+		t.Errorf("got types %q and %q, want CCard and none", balances[2].Type, balances[3].Type)
+	}
+}
@@ -0,0 +1,240 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/category"
+	"github.com/mdhender/qif2json/reader/class"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"io"
+	"strings"
+)
+
+// CategoryMap renames categories and classes.
+//
+// The keys are the names to rename, as colon separated paths, and the
+// values are the new names. A name that isn't a key is renamed by its
+// closest parent that is, so mapping "Auto" to "Car" renames "Auto:Gas"
+// to "Car:Gas" unless "Auto:Gas" has a mapping of its own. Mapping a
+// category to an empty name removes the category from the transactions.
+type CategoryMap struct {
+	Categories map[string]string `json:"categories"`
+	Classes    map[string]string `json:"classes"`
+}
+
+// ReadCategoryMap reads a mapping file, which is a JSON document like
+//
+//	{"categories": {"Auto:Gas": "Car:Fuel", "Auto": "Car"},
+//	 "classes":    {"Biz": "Business"}}
+func ReadCategoryMap(r io.Reader) (*CategoryMap, error) {
+	var m CategoryMap
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("category map: %w", err)
+	}
+	return &m, nil
+}
+
+// Category returns the new name of a category and true if it is mapped.
+func (m *CategoryMap) Category(name string) (string, bool) {
+	return rename(m.Categories, name)
+}
+
+// Class returns the new name of a class and true if it is mapped.
+func (m *CategoryMap) Class(name string) (string, bool) {
+	return rename(m.Classes, name)
+}
+
+// rename returns the name with its longest mapped prefix replaced.
+func rename(mapping map[string]string, name string) (string, bool) {
+	for prefix, rest := name, ""; ; {
+		if to, ok := mapping[prefix]; ok {
+			if to == "" {
+				return "", true
+			}
+			return to + rest, true
+		}
+		i := strings.LastIndexByte(prefix, ':')
+		if i == -1 {
+			return name, false
+		}
+		prefix, rest = name[:i], name[i:]
+	}
+}
+
+// isTarget returns true if the name is one that the mapping renames to,
+// so it is already mapped.
+func isTarget(mapping map[string]string, name string) bool {
+	for _, to := range mapping {
+		if to == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Unmapped is a category or class that the map doesn't rename.
+type Unmapped struct {
+	Name string
+	Uses int // the number of transactions and splits that use it
+}
+
+// CategoryReport lists the categories and classes that weren't mapped, in
+// the order they were first seen. A name that the map renames to is
+// already mapped and isn't reported, but its parents and subcategories
+// are. Categories aren't reported if the map has no categories, and
+// classes aren't reported if it has no classes.
+type CategoryReport struct {
+	Categories []*Unmapped
+	Classes    []*Unmapped
+}
+
+// UnmappedError is returned in strict mode when some categories or classes aren't mapped.
+type UnmappedError struct {
+	Report *CategoryReport
+}
+
+func (e *UnmappedError) Error() string {
+	var names []string
+	for _, u := range e.Report.Categories {
+		names = append(names, u.Name)
+	}
+	for _, u := range e.Report.Classes {
+		names = append(names, "/"+u.Name)
+	}
+	return fmt.Sprintf("category map: unmapped categories and classes: %s", strings.Join(names, ", "))
+}
+
+// RemapCategories renames the categories and classes in the category and
+// class lists, the transactions and their splits, the investment
+// transactions and the memorized transactions. Renaming two categories to
+// the same name merges them in the category list, keeping the first.
+//
+// If strict is true and anything isn't mapped, nothing is renamed and the
+// report is returned with an UnmappedError.
+func RemapCategories(r *reader.Reader, m *CategoryMap, strict bool) (*CategoryReport, error) {
+	var report CategoryReport
+	unmapped := make(map[string]*Unmapped)
+	check := func(list *[]*Unmapped, name string, isClass, used bool) {
+		if name == "" {
+			return
+		}
+		mapping, key := m.Categories, "L"+name
+		if isClass {
+			mapping, key = m.Classes, "/"+name
+		}
+		if len(mapping) == 0 {
+			return
+		} else if _, ok := rename(mapping, name); ok || isTarget(mapping, name) {
+			return
+		}
+		u, ok := unmapped[key]
+		if !ok {
+			u = &Unmapped{Name: name}
+			unmapped[key] = u
+			*list = append(*list, u)
+		}
+		if used {
+			u.Uses++
+		}
+	}
+	eachName(r, func(name *string, isClass, used bool) {
+		if isClass {
+			check(&report.Classes, *name, true, used)
+		} else {
+			check(&report.Categories, *name, false, used)
+		}
+	})
+	if strict && (len(report.Categories) != 0 || len(report.Classes) != 0) {
+		return &report, &UnmappedError{Report: &report}
+	}
+
+	eachName(r, func(name *string, isClass, used bool) {
+		if isClass {
+			*name, _ = m.Class(*name)
+		} else {
+			*name, _ = m.Category(*name)
+		}
+	})
+
+	// merge the entries in the lists that now have the same name
+	if r.Categories != nil {
+		seen := make(map[string]bool)
+		var records []*category.Record
+		for _, record := range r.Categories.Records {
+			if record.Name != "" && !seen[record.Name] {
+				seen[record.Name] = true
+				records = append(records, record)
+			}
+		}
+		r.Categories.Records = records
+	}
+	if r.Classes != nil {
+		seen := make(map[string]bool)
+		var records []*class.Record
+		for _, record := range r.Classes.Records {
+			if record.Name != "" && !seen[record.Name] {
+				seen[record.Name] = true
+				records = append(records, record)
+			}
+		}
+		r.Classes.Records = records
+	}
+
+	return &report, nil
+}
+
+// eachName calls fn with a pointer to every category and class name in the reader.
+// The flag used is false for the names in the category and class lists.
+func eachName(r *reader.Reader, fn func(name *string, isClass, used bool)) {
+	if r.Categories != nil {
+		for _, record := range r.Categories.Records {
+			fn(&record.Name, false, false)
+		}
+	}
+	if r.Classes != nil {
+		for _, record := range r.Classes.Records {
+			fn(&record.Name, true, false)
+		}
+	}
+	for _, records := range [][]*transaction.Record{r.Transactions, r.Memorized} {
+		for _, t := range records {
+			fn(&t.Category, false, true)
+			fn(&t.Class, true, true)
+			for _, split := range t.Split {
+				fn(&split.Category, false, true)
+				fn(&split.Class, true, true)
+			}
+		}
+	}
+	for _, t := range r.Investments {
+		fn(&t.Category, false, true)
+		fn(&t.Class, true, true)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCategoryMap(t *testing.T) {
+	m, err := ReadCategoryMap(strings.NewReader(`{"categories": {"Auto": "Car"}, "classes": {"Biz": "Business"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := m.Category("Auto:Gas"); name != "Car:Gas" || !ok {
+		t.Errorf("Category: got %q %v, want \"Car:Gas\" true", name, ok)
+	}
+	if name, ok := m.Class("Home"); name != "Home" || ok {
+		t.Errorf("Class: got %q %v, want \"Home\" false", name, ok)
+	}
+
+	// a misspelled key would otherwise read as an empty map
+	if _, err := ReadCategoryMap(strings.NewReader(`{"category": {"Auto": "Car"}}`)); err == nil {
+		t.Errorf("ReadCategoryMap: unknown field: got no error")
+	}
+}
+
+func TestRemapCategoriesReport(t *testing.T) {
+	m := &CategoryMap{Categories: map[string]string{"Auto": "Car:Vehicle", "Gifts": ""}}
+	r := &reader.Reader{Transactions: []*transaction.Record{
+		{Line: 1, Category: "Auto:Gas"},          // renamed by its parent
+		{Line: 2, Category: "Car:Vehicle"},       // a target
+		{Line: 3, Category: "Car"},               // the parent of a target
+		{Line: 4, Category: "Car:Vehicle:Tires"}, // a subcategory of a target
+		{Line: 5, Category: "Ca"},                // a prefix of a target
+		{Line: 6, Category: "Gifts"},             // removed
+		{Line: 7, Category: "Car"},
+	}}
+	report, err := RemapCategories(r, m, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Unmapped
+	for _, u := range report.Categories {
+		got = append(got, *u)
+	}
+	want := []Unmapped{{"Car", 2}, {"Car:Vehicle:Tires", 1}, {"Ca", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unmapped: got %v, want %v", got, want)
+	}
+	var categories []string
+	for _, xact := range r.Transactions {
+		categories = append(categories, xact.Category)
+	}
+	if want := []string{"Car:Vehicle:Gas", "Car:Vehicle", "Car", "Car:Vehicle:Tires", "Ca", "", "Car"}; !reflect.DeepEqual(categories, want) {
+		t.Errorf("categories: got %q, want %q", categories, want)
+	}
+
+	if _, err := RemapCategories(r, m, true); err == nil {
+		t.Errorf("RemapCategories: strict: got no error")
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mdhender/qif2json/reader"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PayeeMatch is how a payee rule matches a payee.
+type PayeeMatch string
+
+const (
+	// ExactMatch matches a payee that is the pattern, ignoring case and extra spaces.
+	ExactMatch PayeeMatch = "exact"
+	// PrefixMatch matches a payee that starts with the pattern, ignoring case and extra spaces.
+	PrefixMatch PayeeMatch = "prefix"
+	// RegexpMatch matches a payee with a Go regular expression. The payee of
+	// the rule may refer to the submatches as $1 or ${name}.
+	RegexpMatch PayeeMatch = "regexp"
+)
+
+// PayeeRule replaces the payees it matches with its payee.
+type PayeeRule struct {
+	Match   PayeeMatch `json:"match"`
+	Pattern string     `json:"pattern"`
+	Payee   string     `json:"payee"`
+	key     string     // the canonical pattern, for exact and prefix matches
+	re      *regexp.Regexp
+}
+
+func (rule *PayeeRule) String() string {
+	return fmt.Sprintf("%s %q", rule.Match, rule.Pattern)
+}
+
+// PayeeRules are the rules to normalize payees, in the order they are tried.
+type PayeeRules struct {
+	Rules []*PayeeRule `json:"rules"`
+}
+
+// ReadPayeeRules reads a rules file, which is a JSON document like
+//
+//	{"rules": [
+//	  {"match": "exact",  "pattern": "AMZN Mktp US", "payee": "Amazon"},
+//	  {"match": "prefix", "pattern": "SHELL OIL", "payee": "Shell"},
+//	  {"match": "regexp", "pattern": "^SQ \\*(.+?) \\d+", "payee": "$1"}
+//	]}
+func ReadPayeeRules(r io.Reader) (*PayeeRules, error) {
+	var rules PayeeRules
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, fmt.Errorf("payee rules: %w", err)
+	}
+	for i, rule := range rules.Rules {
+		switch rule.Match {
+		case ExactMatch, PrefixMatch:
+			rule.key = canonical(rule.Pattern)
+		case RegexpMatch:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("payee rules: rule %d: %w", i+1, err)
+			}
+			rule.re = re
+		default:
+			return nil, fmt.Errorf("payee rules: rule %d: unknown match %q", i+1, rule.Match)
+		}
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return nil, fmt.Errorf("payee rules: rule %d: missing pattern", i+1)
+		}
+	}
+	return &rules, nil
+}
+
+// Apply returns the payee as rewritten by the first rule that matches it.
+// If no rule matches, it returns the payee unchanged and a nil rule.
+func (rules *PayeeRules) Apply(payee string) (string, *PayeeRule) {
+	for _, rule := range rules.Rules {
+		switch rule.Match {
+		case ExactMatch:
+			if canonical(payee) == rule.key {
+				return rule.Payee, rule
+			}
+		case PrefixMatch:
+			if strings.HasPrefix(canonical(payee), rule.key) {
+				return rule.Payee, rule
+			}
+		case RegexpMatch:
+			if match := rule.re.FindStringSubmatchIndex(payee); match != nil {
+				return strings.TrimSpace(string(rule.re.ExpandString(nil, rule.Payee, payee, match))), rule
+			}
+		}
+	}
+	return payee, nil
+}
+
+// PayeeChange records the rule that was applied to the payee of a record.
+type PayeeChange struct {
+	Line     int
+	Date     string
+	Account  string
+	Record   string // "transaction", "memorized" or "investment"
+	Original string
+	Payee    string
+	Rule     *PayeeRule // nil if no rule matched
+}
+
+// NormalizePayees applies the rules to the payee of each transaction, memorized
+// transaction and investment that has one. The payee is replaced and the original
+// is saved in OriginalPayee. It returns a change for every record with a payee,
+// whether a rule matched or not, in the order the records were read.
+func NormalizePayees(r *reader.Reader, rules *PayeeRules) []*PayeeChange {
+	var changes []*PayeeChange
+	apply := func(kind string, line int, date, account string, payee, originalPayee *string) {
+		if *payee == "" {
+			return
+		}
+		normalized, rule := rules.Apply(*payee)
+		changes = append(changes, &PayeeChange{Line: line, Date: date, Account: account, Record: kind, Original: *payee, Payee: normalized, Rule: rule})
+		if rule != nil {
+			*originalPayee, *payee = *payee, normalized
+		}
+	}
+	for _, t := range r.Transactions {
+		apply("transaction", t.Line, t.Date, t.Account, &t.Payee, &t.OriginalPayee)
+	}
+	for _, t := range r.Memorized {
+		apply("memorized", t.Line, t.Date, t.Account, &t.Payee, &t.OriginalPayee)
+	}
+	for _, record := range r.Investments {
+		apply("investment", record.Line, record.Date, record.Account, &record.Payee, &record.OriginalPayee)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Line < changes[j].Line
+	})
+	return changes
+}
+
+// canonical returns the text in upper case with runs of spaces replaced by a single space.
+func canonical(text string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,86 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePayees(t *testing.T) {
+	rules, err := ReadPayeeRules(strings.NewReader(`{"rules": [
+		{"match": "exact", "pattern": "rent", "payee": "Landlord"},
+		{"match": "prefix", "pattern": "ACME  DIV", "payee": "Acme"},
+		{"match": "regexp", "pattern": "^SQ \\*(.+?) \\d+$", "payee": "$1"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &reader.Reader{
+		Transactions: []*transaction.Record{
+			{Line: 20, Payee: "SQ *Corner Cafe 1234"},
+			{Line: 24, Payee: "Grocer"},
+			{Line: 28},
+		},
+		Memorized:   []*transaction.Record{{Line: 2, Payee: "RENT"}},
+		Investments: []*investment.Record{{Line: 11, Payee: "acme div 0201"}},
+	}
+	changes := NormalizePayees(r, rules)
+
+	want := []struct {
+		line     int
+		record   string
+		original string
+		payee    string
+		rule     *PayeeRule
+	}{
+		{2, "memorized", "RENT", "Landlord", rules.Rules[0]},
+		{11, "investment", "acme div 0201", "Acme", rules.Rules[1]},
+		{20, "transaction", "SQ *Corner Cafe 1234", "Corner Cafe", rules.Rules[2]},
+		{24, "transaction", "Grocer", "Grocer", nil},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d", len(changes), len(want))
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Line != w.line || c.Record != w.record || c.Original != w.original || c.Payee != w.payee || c.Rule != w.rule {
+			t.Errorf("change %d: got %d %s %q -> %q, want %d %s %q -> %q", i, c.Line, c.Record, c.Original, c.Payee, w.line, w.record, w.original, w.payee)
+		}
+	}
+
+	if m := r.Memorized[0]; m.Payee != "Landlord" || m.OriginalPayee != "RENT" {
+		t.Errorf("memorized: payee %q, original %q", m.Payee, m.OriginalPayee)
+	}
+	if i := r.Investments[0]; i.Payee != "Acme" || i.OriginalPayee != "acme div 0201" {
+		t.Errorf("investment: payee %q, original %q", i.Payee, i.OriginalPayee)
+	}
+	if x := r.Transactions[1]; x.Payee != "Grocer" || x.OriginalPayee != "" {
+		t.Errorf("unmatched transaction: payee %q, original %q", x.Payee, x.OriginalPayee)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+)
+
+// PostingKind is what a posting changes the balance of.
+type PostingKind string
+
+const (
+	// AccountPosting is the posting to the account the transaction is in.
+	AccountPosting PostingKind = "account"
+	// TransferPosting is a split that transfers to another account.
+	TransferPosting PostingKind = "transfer"
+	// OpeningBalancePosting is a split that transfers to the account the
+	// transaction is in, which is how Quicken records an opening balance.
+	OpeningBalancePosting PostingKind = "opening_balance"
+	// CategoryPosting is a split with a category.
+	CategoryPosting PostingKind = "category"
+	// UncategorizedPosting is a split with neither a category nor a transfer.
+	UncategorizedPosting PostingKind = "uncategorized"
+)
+
+// Entry is a transaction as double-entry postings.
+// The amounts of the postings add up to zero.
+type Entry struct {
+	Transaction *Transaction
+	Postings    []*Posting
+	// Unbalanced is true if the splits don't add up to the amount of the
+	// transaction. The posting to the account is the total of the splits,
+	// so Difference is the amount of the transaction less that posting.
+	Unbalanced bool
+	Difference decimal.Decimal
+}
+
+// Posting is a change to the balance of an account or category.
+// The amount is positive for a debit, which adds to an asset account or
+// an expense, and negative for a credit.
+type Posting struct {
+	Kind     PostingKind
+	Account  string // the account, for all but category and uncategorized postings
+	Category string // the category, for a category posting
+	Amount   decimal.Decimal
+	Class    string
+	Memo     string
+	Split    *Split // the split of the posting, nil for the account posting
+}
+
+// Postings returns the transactions as double-entry postings.
+//
+// The first posting of each entry is to the account the transaction is
+// in, for the total of the splits. It is followed by a posting for each
+// split, with the amount of the split reversed.
+func Postings(transactions []*Transaction) []*Entry {
+	var entries []*Entry
+	for _, t := range transactions {
+		e := Entry{Transaction: t}
+		owner := Posting{Kind: AccountPosting, Account: t.Account}
+		e.Postings = append(e.Postings, &owner)
+		for _, split := range t.Split {
+			owner.Amount = owner.Amount.Add(split.Amount)
+			p := Posting{Amount: split.Amount.Neg(), Class: split.Class, Memo: split.Memo, Split: split}
+			switch {
+			case split.Account != "" && split.Account == t.Account:
+				p.Kind, p.Account = OpeningBalancePosting, split.Account
+			case split.Account != "":
+				p.Kind, p.Account = TransferPosting, split.Account
+			case split.Category != "":
+				p.Kind, p.Category = CategoryPosting, split.Category
+			default:
+				p.Kind = UncategorizedPosting
+			}
+			e.Postings = append(e.Postings, &p)
+		}
+		if !t.Amount.Equal(owner.Amount) {
+			e.Unbalanced, e.Difference = true, t.Amount.Sub(owner.Amount)
+		}
+		entries = append(entries, &e)
+	}
+	return entries
+}
@@ -0,0 +1,89 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"testing"
+)
+
+func TestPostings(t *testing.T) {
+	split := func(account, category, amount string) *Split {
+		return &Split{Account: account, Category: category, Amount: decimal.MustParse(amount)}
+	}
+	transactions := []*Transaction{
+		{Account: "Checking", Amount: decimal.MustParse("-75"), Split: []*Split{
+			split("", "Auto:Fuel", "-25.00"), split("Visa", "", "-50.00"),
+		}},
+		{Account: "Checking", Amount: decimal.MustParse("100.00"), Split: []*Split{split("Checking", "", "100.00")}},
+		{Account: "Checking", Amount: decimal.MustParse("-5.00"), Split: []*Split{split("", "", "-5.00")}},
+		// the splits add up to 70.00, not 75.00
+		{Account: "Checking", Amount: decimal.MustParse("-75.00"), Split: []*Split{
+			split("", "Food", "-30.00"), split("", "Dining", "-40.00"),
+		}},
+	}
+	type posting struct {
+		kind    PostingKind
+		account string
+		amount  string
+	}
+	for i, tc := range []struct {
+		postings   []posting
+		unbalanced bool
+		difference string
+	}{
+		{[]posting{{AccountPosting, "Checking", "-75.00"}, {CategoryPosting, "Auto:Fuel", "25.00"}, {TransferPosting, "Visa", "50.00"}}, false, "0"},
+		{[]posting{{AccountPosting, "Checking", "100.00"}, {OpeningBalancePosting, "Checking", "-100.00"}}, false, "0"},
+		{[]posting{{AccountPosting, "Checking", "-5.00"}, {UncategorizedPosting, "", "5.00"}}, false, "0"},
+		{[]posting{{AccountPosting, "Checking", "-70.00"}, {CategoryPosting, "Food", "30.00"}, {CategoryPosting, "Dining", "40.00"}}, true, "-5.00"},
+	} {
+		e := Postings(transactions)[i]
+		var sum decimal.Decimal
+		var got []posting
+		for _, p := range e.Postings {
+			name := p.Account
+			if p.Kind == CategoryPosting {
+				name = p.Category
+			}
+			got = append(got, posting{p.Kind, name, p.Amount.String()})
+			sum = sum.Add(p.Amount)
+		}
+		if len(got) != len(tc.postings) {
+			t.Errorf("entry %d: got postings %v, want %v", i, got, tc.postings)
+		} else {
+			for j := range got {
+				if got[j] != tc.postings[j] {
+					t.Errorf("entry %d: posting %d: got %v, want %v", i, j, got[j], tc.postings[j])
+				}
+			}
+		}
+		if !sum.IsZero() {
+			t.Errorf("entry %d: postings add up to %s, want zero", i, sum)
+		}
+		if e.Unbalanced != tc.unbalanced || !e.Difference.Equal(decimal.MustParse(tc.difference)) {
+			t.Errorf("entry %d: got unbalanced %v by %s, want %v by %s", i, e.Unbalanced, e.Difference, tc.unbalanced, tc.difference)
+		}
+	}
+}
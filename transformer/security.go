@@ -0,0 +1,61 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/reader"
+)
+
+// UnresolvedSecurities returns the securities that investments and prices
+// refer to that aren't in the security list, in the order they are first used.
+// Investments refer to a security by name and prices by ticker, so a security
+// that isn't listed can't be linked to its prices.
+// A price may also name a security that doesn't have a ticker.
+func UnresolvedSecurities(r *reader.Reader) []string {
+	names, tickers := make(map[string]bool), make(map[string]bool)
+	if r.Securities != nil {
+		for _, record := range r.Securities.Records {
+			names[record.Name] = true
+			if record.Ticker != "" {
+				tickers[record.Ticker] = true
+			}
+		}
+	}
+	var unresolved []string
+	seen := make(map[string]bool)
+	for _, record := range r.Investments {
+		if record.Security != "" && !names[record.Security] && !seen[record.Security] {
+			seen[record.Security] = true
+			unresolved = append(unresolved, record.Security)
+		}
+	}
+	for _, record := range r.Prices {
+		if record.Ticker != "" && !tickers[record.Ticker] && !names[record.Ticker] && !seen[record.Ticker] {
+			seen[record.Ticker] = true
+			unresolved = append(unresolved, record.Ticker)
+		}
+	}
+	return unresolved
+}
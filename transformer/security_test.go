@@ -0,0 +1,60 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/reader"
+	"github.com/mdhender/qif2json/reader/investment"
+	"github.com/mdhender/qif2json/reader/security"
+	"github.com/mdhender/qif2json/reader/transaction"
+	"reflect"
+	"testing"
+)
+
+func TestUnresolvedSecurities(t *testing.T) {
+	r := &reader.Reader{
+		Securities: &security.Section{Records: []*security.Record{
+			{Name: "ACME Corp", Ticker: "ACME"},
+			{Name: "Bond Fund"},
+		}},
+		Investments: []*investment.Record{
+			{Security: "ACME Corp"},
+			{Security: "Other Fund"},
+			{Security: "Other Fund"},
+			{Security: "ACME"}, // a ticker isn't a name
+			{},
+		},
+		Prices: []*transaction.Record{
+			{Ticker: "ACME"},
+			{Ticker: "Bond Fund"}, // a security without a ticker is priced by name
+			{Ticker: "XYZ"},
+			{Ticker: "Other Fund"},
+		},
+	}
+	want := []string{"Other Fund", "ACME", "XYZ"}
+	if got := UnresolvedSecurities(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -24,34 +24,41 @@
 
 package transformer
 
-import "github.com/mdhender/qif2json/reader/transaction"
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"github.com/mdhender/qif2json/reader/transaction"
+)
 
 type Transaction struct {
 	Line          int
 	Type          string
 	Account       string
-	Address       []string // Up to five lines (the sixth line is an optional message)
+	Address       []string        // Up to five lines (the sixth line is an optional message)
+	Amount        decimal.Decimal // the total of the transaction, which the splits should add up to
 	Category      string
 	ClearedStatus string
-	Commission    string
+	Commission    decimal.Decimal
 	Date          string
-	Interest      string
+	Interest      decimal.Decimal
 	Memo          string
 	MemorizedFlag string
-	Quantity      string
+	Quantity      decimal.Decimal
 	Payee         string
-	Price         string
+	OriginalPayee string
+	Price         decimal.Decimal
 	RefNo         string
 	Split         []*Split
 	Ticker        string
 }
 
 type Split struct {
-	Line     int
-	Account  string
-	Amount   string
-	Category string
-	Memo     string
+	Line       int
+	Account    string
+	Amount     decimal.Decimal
+	Category   string
+	Class      string
+	Memo       string
+	TransferID string // set by MatchTransfers
 }
 
 func NormalizeSplits(transactions []*transaction.Record) []*Transaction {
@@ -62,9 +69,11 @@ func NormalizeSplits(transactions []*transaction.Record) []*Transaction {
 			Type:          t.Type,
 			Date:          t.Date,
 			Account:       t.Account,
+			Amount:        t.AmountTCode,
 			ClearedStatus: t.ClearedStatus,
 			Memo:          t.Memo,
 			Payee:         t.Payee,
+			OriginalPayee: t.OriginalPayee,
 			RefNo:         t.RefNo,
 		}
 		if len(t.Split) == 0 {
@@ -74,6 +83,7 @@ func NormalizeSplits(transactions []*transaction.Record) []*Transaction {
 				Account:  t.ToAccount,
 				Amount:   t.AmountTCode,
 				Category: t.Category,
+				Class:    t.Class,
 				Memo:     t.Memo,
 			}
 			xact.Split = append(xact.Split, &split)
@@ -82,8 +92,9 @@ func NormalizeSplits(transactions []*transaction.Record) []*Transaction {
 				split := Split{
 					Line:     line.Line,
 					Account:  line.Account,
-					Amount:   line.Amount,
+					Amount:   line.AmountOrZero(),
 					Category: line.Category,
+					Class:    line.Class,
 					Memo:     line.Memo,
 				}
 				if i == 0 && split.Account == "" {
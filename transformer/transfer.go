@@ -0,0 +1,236 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"fmt"
+	"github.com/mdhender/qif2json/decimal"
+	"time"
+)
+
+// MatchOptions control how the halves of a transfer are paired.
+type MatchOptions struct {
+	// Days is how many days apart the dates of the two halves may be.
+	// Zero requires them to have the same date.
+	Days int
+}
+
+// Transfer is a pair of splits that record the same transfer in two accounts.
+// From is the half in the account the money left, and To is the half in the
+// account it arrived in. Both splits have the ID of the transfer.
+type Transfer struct {
+	ID     string
+	Amount decimal.Decimal // the amount moved, never negative
+	From   Half
+	To     Half
+}
+
+// Half is one side of a transfer: the split of a transaction that names the other account.
+type Half struct {
+	Transaction *Transaction
+	Split       *Split
+}
+
+// Transfers is the result of matching the transfers in a list of transactions.
+type Transfers struct {
+	Matched   []*Transfer
+	Unmatched []Half // the halves whose other half wasn't found
+}
+
+// MatchTransfers pairs the two halves of each transfer between accounts.
+//
+// A transfer from Checking to Savings is a split in a Checking transaction
+// with the Account "Savings" and a split in a Savings transaction with the
+// Account "Checking". The halves match if their amounts are opposite and
+// their dates are within the window. Splits of split transactions are
+// matched just like transactions with a single split. When there is more
+// than one candidate, the closest date wins, then the first one read.
+//
+// A split that transfers to the account it is in, which is how Quicken
+// records an opening balance, isn't a transfer. A half in a transaction
+// whose date can't be parsed is never matched.
+func MatchTransfers(transactions []*Transaction, opts MatchOptions) *Transfers {
+	var halves []*half
+	candidates := make(map[string][]*half)
+	for _, t := range transactions {
+		for _, split := range t.Split {
+			if split.Account == "" || split.Account == t.Account {
+				continue
+			}
+			h := &half{Half: Half{Transaction: t, Split: split}}
+			halves = append(halves, h)
+			if h.day, h.dated = day(t.Date); !h.dated {
+				// without a date, there is no window to match it in
+				continue
+			}
+			// the other half is in the other account, with the opposite amount
+			key := pairKey(split.Account, t.Account, split.Amount.Neg())
+			candidates[key] = append(candidates[key], h)
+		}
+	}
+
+	var result Transfers
+	for _, h := range halves {
+		if h.matched || !h.dated {
+			continue
+		}
+		var best *half
+		for _, c := range candidates[pairKey(h.Transaction.Account, h.Split.Account, h.Split.Amount)] {
+			if c.matched || c == h || abs(c.day-h.day) > opts.Days {
+				continue
+			} else if best == nil || abs(c.day-h.day) < abs(best.day-h.day) {
+				best = c
+			}
+		}
+		if best == nil {
+			continue
+		}
+		h.matched, best.matched = true, true
+
+		t := Transfer{ID: fmt.Sprintf("transfer:%d", len(result.Matched)+1), Amount: h.Split.Amount.Abs(), From: h.Half, To: best.Half}
+		if h.Split.Amount.Sign() > 0 {
+			t.From, t.To = best.Half, h.Half
+		}
+		t.From.Split.TransferID, t.To.Split.TransferID = t.ID, t.ID
+		result.Matched = append(result.Matched, &t)
+	}
+	for _, h := range halves {
+		if !h.matched {
+			result.Unmatched = append(result.Unmatched, h.Half)
+		}
+	}
+	return &result
+}
+
+// CollapseTransfers returns the transactions with one half of each matched
+// transfer removed, so that each transfer is recorded once.
+//
+// The half that is removed is a transaction with a single split. If both
+// halves are transactions with a single split, the To half is removed.
+// If both halves are splits of split transactions, the To split is removed
+// from a copy of its transaction and the amount of the copy is reduced by
+// the amount of the split. A copy without any splits left is removed.
+// The transactions passed in are not changed.
+func CollapseTransfers(transactions []*Transaction, transfers *Transfers) []*Transaction {
+	removed := make(map[*Transaction]bool)
+	removedSplits := make(map[*Transaction]map[*Split]bool)
+	for _, t := range transfers.Matched {
+		if len(t.To.Transaction.Split) == 1 {
+			removed[t.To.Transaction] = true
+		} else if len(t.From.Transaction.Split) == 1 {
+			removed[t.From.Transaction] = true
+		} else {
+			if removedSplits[t.To.Transaction] == nil {
+				removedSplits[t.To.Transaction] = make(map[*Split]bool)
+			}
+			removedSplits[t.To.Transaction][t.To.Split] = true
+		}
+	}
+	var collapsed []*Transaction
+	for _, t := range transactions {
+		if removed[t] {
+			continue
+		} else if splits := removedSplits[t]; splits != nil {
+			xact := *t
+			xact.Split = nil
+			for _, split := range t.Split {
+				if splits[split] {
+					xact.Amount = xact.Amount.Sub(split.Amount)
+				} else {
+					xact.Split = append(xact.Split, split)
+				}
+			}
+			if len(xact.Split) == 0 {
+				continue
+			}
+			t = &xact
+		}
+		collapsed = append(collapsed, t)
+	}
+	return collapsed
+}
+
+// InvestmentTransfer is the cash an investment action moves between
+// an investment account and another account.
+type InvestmentTransfer struct {
+	Account         string // the investment account
+	TransferAccount string
+	Date            string
+	Amount          decimal.Decimal // the change to the investment account
+}
+
+// DropInvestmentTransfers returns the transactions without the halves of
+// the transfers that are also recorded by an investment action. The
+// investment action is always kept, so a half is only removed if it is a
+// transaction with a single split on the same date. The transactions
+// passed in are not changed.
+func DropInvestmentTransfers(transactions []*Transaction, transfers []InvestmentTransfer) []*Transaction {
+	counts := make(map[string]int)
+	for _, t := range transfers {
+		counts[t.Date+"\x00"+pairKey(t.Account, t.TransferAccount, t.Amount)]++
+	}
+	var kept []*Transaction
+	for _, t := range transactions {
+		if len(t.Split) == 1 && t.Split[0].Account != "" && t.Split[0].Account != t.Account {
+			other := t.Split[0]
+			if key := t.Date + "\x00" + pairKey(other.Account, t.Account, other.Amount.Neg()); counts[key] > 0 {
+				counts[key]--
+				continue
+			}
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+type half struct {
+	Half
+	day     int  // days since the epoch, for the date window
+	dated   bool // false if the date can't be parsed
+	matched bool
+}
+
+// pairKey identifies the halves in one account that transfer to another account.
+// The amount is the change to the "from" account.
+func pairKey(from, to string, amount decimal.Decimal) string {
+	return from + "\x00" + to + "\x00" + amount.Round(2).String()
+}
+
+// day returns the number of days since the epoch of a yyyy/mm/dd date.
+// It returns false if the date can't be parsed.
+func day(date string) (int, bool) {
+	t, err := time.Parse("2006/01/02", date)
+	if err != nil {
+		return 0, false
+	}
+	return int(t.Unix() / (24 * 60 * 60)), true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
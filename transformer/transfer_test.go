@@ -0,0 +1,101 @@
+/*
+ *  qif2json - a QIF data conversion utility
+ *
+ *  Copyright (c) 2021 Michael D Henderson
+ *
+ *  Permission is hereby granted, free of charge, to any person obtaining a copy
+ *  of this software and associated documentation files (the "Software"), to deal
+ *  in the Software without restriction, including without limitation the rights
+ *  to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ *  copies of the Software, and to permit persons to whom the Software is
+ *  furnished to do so, subject to the following conditions:
+ *
+ *  The above copyright notice and this permission notice shall be included in all
+ *  copies or substantial portions of the Software.
+ *
+ *  THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ *  IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ *  FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ *  AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ *  LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ *  OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ *  SOFTWARE.
+ */
+
+package transformer
+
+import (
+	"github.com/mdhender/qif2json/decimal"
+	"testing"
+)
+
+// xact returns a transaction with a split for each pair of account and amount.
+func xact(account, date string, splits ...interface{}) *Transaction {
+	t := &Transaction{Account: account, Date: date}
+	for i := 0; i < len(splits); i += 2 {
+		amount := decimal.MustParse(splits[i+1].(string))
+		t.Split = append(t.Split, &Split{Account: splits[i].(string), Amount: amount})
+		t.Amount = t.Amount.Add(amount)
+	}
+	return t
+}
+
+func TestMatchTransfers(t *testing.T) {
+	from := xact("Checking", "2021/01/01", "Savings", "-100.00")
+	to := xact("Savings", "2021/01/03", "Checking", "100.00")
+	undated := xact("Checking", "", "Savings", "-50.00")
+	undatedTo := xact("Savings", "", "Checking", "50.00")
+	opening := xact("Checking", "2021/01/01", "Checking", "10.00")
+	transactions := []*Transaction{from, to, undated, undatedTo, opening}
+
+	if got := MatchTransfers(transactions, MatchOptions{}); len(got.Matched) != 0 || len(got.Unmatched) != 4 {
+		t.Errorf("same day: got %d matched and %d unmatched, want 0 and 4", len(got.Matched), len(got.Unmatched))
+	}
+
+	got := MatchTransfers(transactions, MatchOptions{Days: 2})
+	if len(got.Matched) != 1 {
+		t.Fatalf("got %d matched, want 1", len(got.Matched))
+	}
+	if m := got.Matched[0]; m.From.Transaction != from || m.To.Transaction != to || m.Amount.String() != "100.00" {
+		t.Errorf("matched %s from %s to %s, want 100.00 from Checking to Savings", m.Amount, m.From.Transaction.Account, m.To.Transaction.Account)
+	} else if from.Split[0].TransferID != m.ID || to.Split[0].TransferID != m.ID {
+		t.Errorf("the splits don't have the transfer ID %q", m.ID)
+	}
+	// the halves without a date are never matched
+	if len(got.Unmatched) != 2 || got.Unmatched[0].Transaction != undated || got.Unmatched[1].Transaction != undatedTo {
+		t.Errorf("got %d unmatched, want the 2 halves without a date", len(got.Unmatched))
+	}
+}
+
+func TestCollapseTransfers(t *testing.T) {
+	// single split to single split: the To half is removed
+	single := xact("Checking", "2021/01/01", "Savings", "-100.00")
+	singleTo := xact("Savings", "2021/01/01", "Checking", "100.00")
+	// split to single split: the single split is removed
+	split := xact("Checking", "2021/01/02", "Savings", "-20.00", "", "-5.00")
+	splitTo := xact("Savings", "2021/01/02", "Checking", "20.00")
+	// split to split: the To split is removed from its transaction
+	paycheck := xact("Checking", "2021/01/03", "", "1000.00", "Savings", "-200.00")
+	deposit := xact("Savings", "2021/01/03", "Checking", "200.00", "", "5.00")
+
+	transactions := []*Transaction{single, singleTo, split, splitTo, paycheck, deposit}
+	transfers := MatchTransfers(transactions, MatchOptions{})
+	if len(transfers.Matched) != 3 {
+		t.Fatalf("got %d matched, want 3", len(transfers.Matched))
+	}
+	got := CollapseTransfers(transactions, transfers)
+	if len(got) != 4 {
+		t.Fatalf("got %d transactions, want 4", len(got))
+	}
+	if got[0] != single || got[1] != split || got[2] != paycheck {
+		t.Errorf("the wrong transactions were removed")
+	}
+	if d := got[3]; d == deposit {
+		t.Errorf("the transaction passed in was changed instead of copied")
+	} else if len(d.Split) != 1 || d.Split[0] != deposit.Split[1] || d.Amount.String() != "5.00" {
+		t.Errorf("got %d splits with an amount of %s, want the 5.00 split", len(d.Split), d.Amount)
+	}
+	if len(deposit.Split) != 2 || deposit.Amount.String() != "205.00" {
+		t.Errorf("the transaction passed in was changed")
+	}
+}